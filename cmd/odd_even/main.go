@@ -1,124 +1,60 @@
 package main
 
 import (
-	"flag"
 	"fmt"
-	"strings"
-	"sync"
-	"time"
+	"os"
 
 	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/algorithms"
 	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/simulator"
-	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/transport"
 	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
 )
 
 func main() {
-	nodeCount := flag.Uint("node-count", 10, "Number of Nodes")
-	inputTypeStr := flag.String("input-type", "random", "Input type")
-	debug := flag.Bool("debug", false, "Enable verbose logging")
-	benchmark := flag.Bool("benchmark", false, "Enable benchmarking metrics")
-	flag.Parse()
-
-	if *debug {
-		fmt.Printf("--- Distributed Sorting Simulator (Odd-Even) ---\n")
-		fmt.Printf("Nodes: %d | Type: %s\n", *nodeCount, *inputTypeStr)
+	opts, err := simulator.ParseFlags(types.OddEven)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(2)
 	}
 
-	var inputType types.InputType
-	switch strings.ToLower(*inputTypeStr) {
-	case "random":
-		inputType = types.Random
-	case "sorted":
-		inputType = types.Sorted
-	case "reverse":
-		inputType = types.Reverse
+	switch opts.Config.ValueType {
+	case types.FloatValues:
+		err = run(opts, simulator.FloatValues)
+	case types.StringValues:
+		err = run(opts, simulator.StringValues)
+	case types.RecordValues:
+		err = run(opts, simulator.RecordValues)
 	default:
-		panic("Invalid input type")
-	}
-
-	cfg := types.Config{
-		NodeCount: uint(*nodeCount),
-		InputType: inputType,
-		Algorithm: types.OddEven,
+		err = run(opts, simulator.IntValues)
 	}
-
-	engine := simulator.NewEngine[algorithms.OddEvenPayload](int(*nodeCount))
-	var wg sync.WaitGroup
-	var startTime time.Time
-
-	initialArray := make([]int, *nodeCount)
-	finalArray := make([]int, *nodeCount)
-
-	if *benchmark {
-		startTime = time.Now()
-	}
-
-	for i := 0; i < int(*nodeCount); i++ {
-		wg.Add(1)
-		go func(id int) {
-			defer wg.Done()
-
-			node := &types.Node[algorithms.OddEvenPayload]{
-				ID:         id,
-				TotalNode:  int(*nodeCount),
-				LeftInbox:  make(chan types.Message[algorithms.OddEvenPayload], 500),
-				RightInbox: make(chan types.Message[algorithms.OddEvenPayload], 500),
-			}
-
-			if id == 0 {
-				node.Position = types.Head
-			} else if id == int(*nodeCount)-1 {
-				node.Position = types.Tail
-			} else {
-				node.Position = types.Middle
-			}
-
-			leftBuf := simulator.NewRoundBuffer(node.LeftInbox)
-			rightBuf := simulator.NewRoundBuffer(node.RightInbox)
-
-			if err := simulator.SetupNode(node, *debug); err != nil {
-				if *debug {
-					fmt.Printf("Error setup node %d: %v\n", id, err)
-				}
-				return
-			}
-
-			time.Sleep(100 * time.Millisecond)
-
-			if err := engine.InitialSetup(node, cfg, leftBuf, rightBuf, *debug); err != nil {
-				if *debug {
-					fmt.Printf("Error discovery node %d: %v\n", id, err)
-				}
-				return
-			}
-
-			val := simulator.GenerateInitialValue(id, cfg)
-			node.Value = algorithms.OddEvenPayload{Value: val}
-
-			initialArray[id] = val
-
-			algorithms.RunOddEven(node, engine, leftBuf, rightBuf, transport.SendMessage[algorithms.OddEvenPayload], *debug)
-
-			finalArray[id] = node.Value.Value
-		}(i)
-	}
-
-	wg.Wait()
-
-	if *nodeCount <= 100 {
-		fmt.Printf("\n--- Results (N=%d) ---\n", *nodeCount)
-		fmt.Printf("Initial: %v\n", initialArray)
-		fmt.Printf("Final:   %v\n", finalArray)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
 	}
+}
 
-	if *benchmark {
-		elapsed := time.Since(startTime)
-		fmt.Printf("\n--- Benchmark Results ---\n")
-		fmt.Printf("Algorithm: Odd-Even Transposition\n")
-		fmt.Printf("Nodes: %d\n", *nodeCount)
-		fmt.Printf("Time: %v\n", elapsed)
+func run[V any](opts simulator.RunOptions, kind simulator.ValueKind[V]) error {
+	var report simulator.Report[V]
+	var err error
+	if opts.Config.BlockSize > 1 {
+		report, err = simulator.Run(opts, simulator.Algorithm[V, algorithms.BlockPayload[V]]{
+			Name:      "Odd-Even Transposition (Block)",
+			Payload:   func(values []V) algorithms.BlockPayload[V] { return algorithms.BlockPayload[V]{Values: values} },
+			Values:    func(p algorithms.BlockPayload[V]) []V { return p.Values },
+			Highlight: algorithms.OddEvenHighlight[algorithms.BlockPayload[V]],
+			Run:       algorithms.RunOddEvenBlock[V],
+		}, kind)
 	} else {
-		fmt.Println("Simulation Complete.")
+		report, err = simulator.Run(opts, simulator.Algorithm[V, algorithms.OddEvenPayload[V]]{
+			Name:      "Odd-Even Transposition",
+			Payload:   func(values []V) algorithms.OddEvenPayload[V] { return algorithms.OddEvenPayload[V]{Value: values[0]} },
+			Values:    func(p algorithms.OddEvenPayload[V]) []V { return []V{p.Value} },
+			Highlight: algorithms.OddEvenHighlight[algorithms.OddEvenPayload[V]],
+			Run:       algorithms.RunOddEven[V],
+		}, kind)
+	}
+	if err != nil {
+		return err
 	}
+	report.Print()
+	return nil
 }
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/algorithms"
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/simulator"
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+func main() {
+	opts, err := simulator.ParseFlags(types.RingRank)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(2)
+	}
+
+	switch opts.Config.ValueType {
+	case types.FloatValues:
+		err = run(opts, simulator.FloatValues)
+	case types.StringValues:
+		err = run(opts, simulator.StringValues)
+	case types.RecordValues:
+		err = run(opts, simulator.RecordValues)
+	default:
+		err = run(opts, simulator.IntValues)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func run[V any](opts simulator.RunOptions, kind simulator.ValueKind[V]) error {
+	name := "Ring Rank"
+	if opts.Config.BlockSize > 1 {
+		name += " (Block)"
+	}
+	report, err := simulator.Run(opts, simulator.Algorithm[V, algorithms.RingPayload[V]]{
+		Name:    name,
+		Payload: func(values []V) algorithms.RingPayload[V] { return algorithms.RingPayload[V]{Values: values} },
+		Values:  func(p algorithms.RingPayload[V]) []V { return p.Values },
+		Run:     algorithms.RunRingRank[V],
+	}, kind)
+	if err != nil {
+		return err
+	}
+	report.Print()
+	return nil
+}
@@ -1,99 +1,70 @@
 package main
 
 import (
-	"flag"
 	"fmt"
-	"strings"
-	"sync"
-	"time"
+	"os"
 
 	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/algorithms"
 	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/simulator"
-	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/transport"
 	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
 )
 
 func main() {
-	nodeCount := flag.Uint("node-count", 10, "Number of Nodes")
-	inputTypeStr := flag.String("input-type", "random", "Input type")
-	debug := flag.Bool("debug", false, "Enable verbose logging")
-	benchmark := flag.Bool("benchmark", false, "Enable benchmarking")
-	flag.Parse()
-
-	var inputType types.InputType
-	switch strings.ToLower(*inputTypeStr) {
-	case "random":
-		inputType = types.Random
-	case "sorted":
-		inputType = types.Sorted
-	case "reverse":
-		inputType = types.Reverse
-	default:
-		panic("Invalid input type")
+	opts, err := simulator.ParseFlags(types.Sasaki)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(2)
 	}
 
-	cfg := types.Config{NodeCount: uint(*nodeCount), InputType: inputType, Algorithm: types.Sasaki}
-	engine := simulator.NewEngine[algorithms.SasakiPayload](int(*nodeCount))
-	var wg sync.WaitGroup
-	var startTime time.Time
-
-	initialArray := make([]int, *nodeCount)
-	finalArray := make([]int, *nodeCount)
-
-	if *benchmark {
-		startTime = time.Now()
-	}
-
-	for i := 0; i < int(*nodeCount); i++ {
-		wg.Add(1)
-		go func(id int) {
-			defer wg.Done()
-			node := &types.Node[algorithms.SasakiPayload]{
-				ID: id, TotalNode: int(*nodeCount),
-				LeftInbox:  make(chan types.Message[algorithms.SasakiPayload], 500),
-				RightInbox: make(chan types.Message[algorithms.SasakiPayload], 500),
-			}
-
-			if id == 0 {
-				node.Position = types.Head
-			} else if id == int(*nodeCount)-1 {
-				node.Position = types.Tail
-			} else {
-				node.Position = types.Middle
-			}
-
-			leftBuf := simulator.NewRoundBuffer(node.LeftInbox)
-			rightBuf := simulator.NewRoundBuffer(node.RightInbox)
-
-			if err := simulator.SetupNode(node, *debug); err != nil {
-				return
-			}
-			time.Sleep(100 * time.Millisecond)
-			if err := engine.InitialSetup(node, cfg, leftBuf, rightBuf, *debug); err != nil {
-				return
-			}
-
-			val := simulator.GenerateInitialValue(id, cfg)
-			node.Value = algorithms.SasakiPayload{Value: val}
-
-			initialArray[id] = val
-
-			algorithms.RunSasaki(node, engine, leftBuf, rightBuf, transport.SendMessage[algorithms.SasakiPayload], *debug)
-
-			finalArray[id] = node.Value.Value
-		}(i)
+	switch opts.Config.ValueType {
+	case types.FloatValues:
+		err = run(opts, simulator.FloatValues)
+	case types.StringValues:
+		err = run(opts, simulator.StringValues)
+	case types.RecordValues:
+		err = run(opts, simulator.RecordValues)
+	default:
+		err = run(opts, simulator.IntValues)
 	}
-	wg.Wait()
-
-	if *nodeCount <= 100 {
-		fmt.Printf("\n--- Results (N=%d) ---\n", *nodeCount)
-		fmt.Printf("Initial: %v\n", initialArray)
-		fmt.Printf("Final:   %v\n", finalArray)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
 	}
+}
 
-	if *benchmark {
-		fmt.Printf("Sasaki Sort Time: %v\n", time.Since(startTime))
+func run[V any](opts simulator.RunOptions, kind simulator.ValueKind[V]) error {
+	var report simulator.Report[V]
+	var err error
+	if opts.Config.BlockSize > 1 {
+		algo := simulator.Algorithm[V, algorithms.SasakiBlockPayload[V]]{
+			Name: "Sasaki Time-Optimal (Block)",
+			Payload: func(values []V) algorithms.SasakiBlockPayload[V] {
+				return algorithms.SasakiBlockPayload[V]{Values: values}
+			},
+			Values:    func(p algorithms.SasakiBlockPayload[V]) []V { return p.Values },
+			State:     algorithms.SasakiBlockState[V],
+			Highlight: algorithms.SasakiBlockHighlight[V],
+			Run:       algorithms.RunSasakiBlock[V],
+		}
+		if opts.Config.Stable {
+			algo.Name += " (Stable)"
+			algo.Run = algorithms.RunStableSasakiBlock[V]
+		}
+		report, err = simulator.Run(opts, algo, kind)
 	} else {
-		fmt.Println("Simulation Complete.")
+		algo := simulator.Algorithm[V, algorithms.SasakiPayload[V]]{
+			Name:      "Sasaki Time-Optimal",
+			Payload:   func(values []V) algorithms.SasakiPayload[V] { return algorithms.SasakiPayload[V]{Value: values[0]} },
+			Values:    func(p algorithms.SasakiPayload[V]) []V { return []V{p.Value} },
+			State:     algorithms.SasakiState[V],
+			Highlight: algorithms.SasakiHighlight[V],
+			Run:       algorithms.RunSasaki[V],
+		}
+		report, err = simulator.Run(opts, algo, kind)
+	}
+	if err != nil {
+		return err
 	}
+	report.Print()
+	return nil
 }
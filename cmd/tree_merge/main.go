@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/algorithms"
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/simulator"
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+func main() {
+	opts, err := simulator.ParseFlags(types.TreeMerge)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(2)
+	}
+
+	switch opts.Config.ValueType {
+	case types.FloatValues:
+		err = run(opts, simulator.FloatValues)
+	case types.StringValues:
+		err = run(opts, simulator.StringValues)
+	case types.RecordValues:
+		err = run(opts, simulator.RecordValues)
+	default:
+		err = run(opts, simulator.IntValues)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func run[V any](opts simulator.RunOptions, kind simulator.ValueKind[V]) error {
+	algo := simulator.Algorithm[V, algorithms.TreePayload[V]]{
+		Name:    "Tree Merge",
+		Payload: func(values []V) algorithms.TreePayload[V] { return algorithms.TreePayload[V]{Values: values} },
+		Values:  func(p algorithms.TreePayload[V]) []V { return p.Values },
+		Order:   algorithms.InOrder,
+		Run:     algorithms.RunTreeMerge[V],
+	}
+	if opts.Config.GatherRoot {
+		algo.Name += " (Gather)"
+		algo.Order = nil
+		algo.Run = algorithms.RunTreeGather[V]
+	}
+	if opts.Config.BlockSize > 1 {
+		algo.Name += " (Block)"
+	}
+
+	report, err := simulator.Run(opts, algo, kind)
+	if err != nil {
+		return err
+	}
+	report.Print()
+	return nil
+}
@@ -0,0 +1,434 @@
+package algorithms
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/simulator"
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/transport"
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+// nextPort hands every simulation its own range of listening ports, away
+// from the transport tests', so runs can go in parallel.
+var nextPort atomic.Int64
+
+func init() {
+	nextPort.Store(20000)
+}
+
+// testOptions returns run options for config with a fresh port range.
+func testOptions(config types.Config) simulator.RunOptions {
+	config.Topology = config.Algorithm.Topology()
+	config.BlockSize = max(config.BlockSize, 1)
+	if config.Termination == "" {
+		config.Termination = types.MessageTermination
+	}
+	n := int64(config.NodeCount)
+	return simulator.RunOptions{
+		Config:    config,
+		Transport: transport.Options{NoDelay: true, PortBase: int(nextPort.Add(n) - n)},
+	}
+}
+
+// runFunc runs an algorithm over one kind of value.
+type runFunc[V any] func(opts simulator.RunOptions) (simulator.Report[V], error)
+
+func runner[V, T any](algo simulator.Algorithm[V, T], kind simulator.ValueKind[V]) runFunc[V] {
+	return func(opts simulator.RunOptions) (simulator.Report[V], error) {
+		return simulator.Run(opts, algo, kind)
+	}
+}
+
+type nodeRun[V, T any] func(n *types.Node[T], engine *simulator.SimulatorEngine[T], sendFunc func(net.Conn, types.Message[T]) error, compare func(a, b V) int, debug bool)
+
+func blockSort[V any](run nodeRun[V, BlockPayload[V]], order func(nodeCount int) []int) simulator.Algorithm[V, BlockPayload[V]] {
+	return simulator.Algorithm[V, BlockPayload[V]]{
+		Payload: func(values []V) BlockPayload[V] { return BlockPayload[V]{Values: values} },
+		Values:  func(p BlockPayload[V]) []V { return p.Values },
+		Order:   order,
+		Run:     run,
+	}
+}
+
+func oddEvenSort[V any]() simulator.Algorithm[V, OddEvenPayload[V]] {
+	return simulator.Algorithm[V, OddEvenPayload[V]]{
+		Payload: func(values []V) OddEvenPayload[V] { return OddEvenPayload[V]{Value: values[0]} },
+		Values:  func(p OddEvenPayload[V]) []V { return []V{p.Value} },
+		Run:     RunOddEven[V],
+	}
+}
+
+func alternativeSort[V any]() simulator.Algorithm[V, AlternativePayload[V]] {
+	return simulator.Algorithm[V, AlternativePayload[V]]{
+		Payload: func(values []V) AlternativePayload[V] { return AlternativePayload[V]{Value: values[0]} },
+		Values:  func(p AlternativePayload[V]) []V { return []V{p.Value} },
+		Run:     RunAlternative[V],
+	}
+}
+
+func sasakiSort[V any](run nodeRun[V, SasakiPayload[V]]) simulator.Algorithm[V, SasakiPayload[V]] {
+	return simulator.Algorithm[V, SasakiPayload[V]]{
+		Payload: func(values []V) SasakiPayload[V] { return SasakiPayload[V]{Value: values[0]} },
+		Values:  func(p SasakiPayload[V]) []V { return []V{p.Value} },
+		Run:     run,
+	}
+}
+
+func sasakiBlockSort[V any](run nodeRun[V, SasakiBlockPayload[V]]) simulator.Algorithm[V, SasakiBlockPayload[V]] {
+	return simulator.Algorithm[V, SasakiBlockPayload[V]]{
+		Payload: func(values []V) SasakiBlockPayload[V] { return SasakiBlockPayload[V]{Values: values} },
+		Values:  func(p SasakiBlockPayload[V]) []V { return p.Values },
+		Run:     run,
+	}
+}
+
+func ringSort[V any]() simulator.Algorithm[V, RingPayload[V]] {
+	return simulator.Algorithm[V, RingPayload[V]]{
+		Payload: func(values []V) RingPayload[V] { return RingPayload[V]{Values: values} },
+		Values:  func(p RingPayload[V]) []V { return p.Values },
+		Run:     RunRingRank[V],
+	}
+}
+
+func treeSort[V any](run nodeRun[V, TreePayload[V]], order func(nodeCount int) []int) simulator.Algorithm[V, TreePayload[V]] {
+	return simulator.Algorithm[V, TreePayload[V]]{
+		Payload: func(values []V) TreePayload[V] { return TreePayload[V]{Values: values} },
+		Values:  func(p TreePayload[V]) []V { return p.Values },
+		Order:   order,
+		Run:     run,
+	}
+}
+
+func mustSchedule(spec string, n int) simulator.Schedule {
+	s, err := simulator.ParseSchedule(spec, n)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func snake(rows int) func(nodeCount int) []int {
+	return func(nodeCount int) []int { return SnakeOrder(rows, nodeCount/rows) }
+}
+
+// sortCase is one algorithm on one topology. Records have runs of equal
+// keys, so they double as the duplicate input; stable cases must keep them
+// in their initial order.
+type sortCase struct {
+	name     string
+	config   types.Config
+	schedule simulator.Schedule
+	stable   bool
+	ints     runFunc[int]
+	records  runFunc[types.Record]
+}
+
+func sortCases() []sortCase {
+	batcher, bitonic, oddeven := mustSchedule("batcher", 6), mustSchedule("bitonic", 6), mustSchedule("oddeven", 6)
+	return []sortCase{
+		{
+			name:    "odd-even",
+			config:  types.Config{Algorithm: types.OddEven, NodeCount: 7},
+			stable:  true,
+			ints:    runner(oddEvenSort[int](), simulator.IntValues),
+			records: runner(oddEvenSort[types.Record](), simulator.RecordValues),
+		},
+		{
+			name:    "odd-even block",
+			config:  types.Config{Algorithm: types.OddEven, NodeCount: 6, BlockSize: 3},
+			stable:  true,
+			ints:    runner(blockSort(RunOddEvenBlock[int], nil), simulator.IntValues),
+			records: runner(blockSort(RunOddEvenBlock[types.Record], nil), simulator.RecordValues),
+		},
+		{
+			name:    "sasaki",
+			config:  types.Config{Algorithm: types.Sasaki, NodeCount: 7},
+			stable:  true,
+			ints:    runner(sasakiSort(RunSasaki[int]), simulator.IntValues),
+			records: runner(sasakiSort(RunSasaki[types.Record]), simulator.RecordValues),
+		},
+		{
+			name:    "sasaki block",
+			config:  types.Config{Algorithm: types.Sasaki, NodeCount: 6, BlockSize: 3},
+			ints:    runner(sasakiBlockSort(RunSasakiBlock[int]), simulator.IntValues),
+			records: runner(sasakiBlockSort(RunSasakiBlock[types.Record]), simulator.RecordValues),
+		},
+		{
+			name:    "stable sasaki block",
+			config:  types.Config{Algorithm: types.Sasaki, NodeCount: 6, BlockSize: 3, Stable: true},
+			stable:  true,
+			ints:    runner(sasakiBlockSort(RunStableSasakiBlock[int]), simulator.IntValues),
+			records: runner(sasakiBlockSort(RunStableSasakiBlock[types.Record]), simulator.RecordValues),
+		},
+		{
+			name:    "alternative",
+			config:  types.Config{Algorithm: types.Alternate, NodeCount: 7},
+			stable:  true,
+			ints:    runner(alternativeSort[int](), simulator.IntValues),
+			records: runner(alternativeSort[types.Record](), simulator.RecordValues),
+		},
+		{
+			name:    "alternative block",
+			config:  types.Config{Algorithm: types.Alternate, NodeCount: 6, BlockSize: 3},
+			stable:  true,
+			ints:    runner(blockSort(RunAlternativeBlock[int], nil), simulator.IntValues),
+			records: runner(blockSort(RunAlternativeBlock[types.Record], nil), simulator.RecordValues),
+		},
+		{
+			name:    "ring",
+			config:  types.Config{Algorithm: types.RingRank, NodeCount: 7},
+			stable:  true,
+			ints:    runner(ringSort[int](), simulator.IntValues),
+			records: runner(ringSort[types.Record](), simulator.RecordValues),
+		},
+		{
+			name:    "ring block",
+			config:  types.Config{Algorithm: types.RingRank, NodeCount: 6, BlockSize: 3},
+			stable:  true,
+			ints:    runner(ringSort[int](), simulator.IntValues),
+			records: runner(ringSort[types.Record](), simulator.RecordValues),
+		},
+		{
+			name:    "shearsort",
+			config:  types.Config{Algorithm: types.Shearsort, NodeCount: 12, Rows: 3},
+			ints:    runner(blockSort(RunShearsort[int], snake(3)), simulator.IntValues),
+			records: runner(blockSort(RunShearsort[types.Record], snake(3)), simulator.RecordValues),
+		},
+		{
+			name:    "shearsort block",
+			config:  types.Config{Algorithm: types.Shearsort, NodeCount: 8, Rows: 4, BlockSize: 3},
+			ints:    runner(blockSort(RunShearsort[int], snake(4)), simulator.IntValues),
+			records: runner(blockSort(RunShearsort[types.Record], snake(4)), simulator.RecordValues),
+		},
+		{
+			name:    "bitonic",
+			config:  types.Config{Algorithm: types.Bitonic, NodeCount: 8},
+			ints:    runner(blockSort(RunBitonic[int], nil), simulator.IntValues),
+			records: runner(blockSort(RunBitonic[types.Record], nil), simulator.RecordValues),
+		},
+		{
+			name:    "bitonic block",
+			config:  types.Config{Algorithm: types.Bitonic, NodeCount: 4, BlockSize: 3},
+			ints:    runner(blockSort(RunBitonic[int], nil), simulator.IntValues),
+			records: runner(blockSort(RunBitonic[types.Record], nil), simulator.RecordValues),
+		},
+		{
+			name:    "tree merge",
+			config:  types.Config{Algorithm: types.TreeMerge, NodeCount: 7},
+			ints:    runner(treeSort(RunTreeMerge[int], InOrder), simulator.IntValues),
+			records: runner(treeSort(RunTreeMerge[types.Record], InOrder), simulator.RecordValues),
+		},
+		{
+			name:    "tree merge block",
+			config:  types.Config{Algorithm: types.TreeMerge, NodeCount: 6, BlockSize: 3},
+			ints:    runner(treeSort(RunTreeMerge[int], InOrder), simulator.IntValues),
+			records: runner(treeSort(RunTreeMerge[types.Record], InOrder), simulator.RecordValues),
+		},
+		{
+			name:    "tree gather",
+			config:  types.Config{Algorithm: types.TreeMerge, NodeCount: 6, BlockSize: 3, GatherRoot: true},
+			ints:    runner(treeSort(RunTreeGather[int], nil), simulator.IntValues),
+			records: runner(treeSort(RunTreeGather[types.Record], nil), simulator.RecordValues),
+		},
+		{
+			name:    "sample sort",
+			config:  types.Config{Algorithm: types.SampleSort, NodeCount: 5},
+			stable:  true,
+			ints:    runner(blockSort(RunSampleSort[int], nil), simulator.IntValues),
+			records: runner(blockSort(RunSampleSort[types.Record], nil), simulator.RecordValues),
+		},
+		{
+			name:    "sample sort block",
+			config:  types.Config{Algorithm: types.SampleSort, NodeCount: 5, BlockSize: 4},
+			stable:  true,
+			ints:    runner(blockSort(RunSampleSort[int], nil), simulator.IntValues),
+			records: runner(blockSort(RunSampleSort[types.Record], nil), simulator.RecordValues),
+		},
+		{
+			name:     "batcher network",
+			config:   types.Config{Algorithm: types.NetworkSort, NodeCount: 6},
+			schedule: batcher,
+			ints:     runner(blockSort(ComparatorNetwork[int](batcher), nil), simulator.IntValues),
+			records:  runner(blockSort(ComparatorNetwork[types.Record](batcher), nil), simulator.RecordValues),
+		},
+		{
+			name:     "bitonic network block",
+			config:   types.Config{Algorithm: types.NetworkSort, NodeCount: 6, BlockSize: 3},
+			schedule: bitonic,
+			ints:     runner(blockSort(ComparatorNetwork[int](bitonic), nil), simulator.IntValues),
+			records:  runner(blockSort(ComparatorNetwork[types.Record](bitonic), nil), simulator.RecordValues),
+		},
+		{
+			name:     "odd-even network",
+			config:   types.Config{Algorithm: types.NetworkSort, NodeCount: 6, BlockSize: 2},
+			schedule: oddeven,
+			stable:   true,
+			ints:     runner(blockSort(ComparatorNetwork[int](oddeven), nil), simulator.IntValues),
+			records:  runner(blockSort(ComparatorNetwork[types.Record](oddeven), nil), simulator.RecordValues),
+		},
+	}
+}
+
+func checkSorted[V any](t *testing.T, report simulator.Report[V], err error, values int) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Verified != nil {
+		t.Fatalf("Not sorted: %v\nfinal: %v", report.Verified, report.Final)
+	}
+	if len(report.Final) != values {
+		t.Fatalf("Got %d values back, expected %d", len(report.Final), values)
+	}
+}
+
+func TestAlgorithmsSort(t *testing.T) {
+	inputs := []struct {
+		name      string
+		inputType types.InputType
+	}{
+		{"random", types.Random},
+		{"sorted", types.Sorted},
+		{"reverse", types.Reverse},
+	}
+
+	for _, tc := range sortCases() {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			values := int(tc.config.NodeCount * max(tc.config.BlockSize, 1))
+
+			for _, input := range inputs {
+				t.Run(input.name, func(t *testing.T) {
+					t.Parallel()
+					config := tc.config
+					config.InputType = input.inputType
+					opts := testOptions(config)
+					opts.Schedule = tc.schedule
+					report, err := tc.ints(opts)
+					checkSorted(t, report, err, values)
+				})
+			}
+
+			t.Run("duplicates", func(t *testing.T) {
+				t.Parallel()
+				config := tc.config
+				config.ValueType = types.RecordValues
+				opts := testOptions(config)
+				opts.Schedule = tc.schedule
+				report, err := tc.records(opts)
+				checkSorted(t, report, err, values)
+				if report.Stability == nil {
+					t.Fatal("No stability report for records")
+				}
+				if tc.stable && report.Stability.Inversions > 0 {
+					t.Errorf("%d equal-key pairs out of order, first at %d: %v", report.Stability.Inversions, report.Stability.First, report.Final)
+				}
+			})
+		})
+	}
+}
+
+func TestTraceReplayMatches(t *testing.T) {
+	config := types.Config{Algorithm: types.OddEven, NodeCount: 5, BlockSize: 2}
+	opts := testOptions(config)
+	opts.Trace = filepath.Join(t.TempDir(), "run.trace")
+	run := runner(blockSort(RunOddEvenBlock[int], nil), simulator.IntValues)
+
+	recorded, err := run(opts)
+	checkSorted(t, recorded, err, 10)
+
+	for _, node := range []int{-1, 2} {
+		replayOpts := testOptions(config)
+		replayOpts.Replay = simulator.ReplayOptions{Path: opts.Trace, Node: node}
+		replayed, err := run(replayOpts)
+		if err != nil {
+			t.Fatalf("Replay of node %d failed: %v", node, err)
+		}
+		if len(replayed.Replay) == 0 {
+			t.Fatalf("Replay of node %d checked no nodes", node)
+		}
+		for _, r := range replayed.Replay {
+			if r.Divergence != nil {
+				t.Errorf("Node %d diverged from the recording: %v", r.Node, r.Divergence)
+			}
+		}
+		if node < 0 && !slices.Equal(replayed.Final, recorded.Final) {
+			t.Errorf("Replay ended with %v, the recording with %v", replayed.Final, recorded.Final)
+		}
+	}
+}
+
+func TestSnapshotsEndWithFinalArray(t *testing.T) {
+	opts := testOptions(types.Config{Algorithm: types.Shearsort, NodeCount: 6, Rows: 2, BlockSize: 2})
+	opts.Snapshots = true
+	report, err := runner(blockSort(RunShearsort[int], snake(2)), simulator.IntValues)(opts)
+	checkSorted(t, report, err, 12)
+
+	if len(report.Snapshots) < 2 {
+		t.Fatalf("Got %d snapshots, expected the initial state and every round", len(report.Snapshots))
+	}
+	first, last := report.Snapshots[0], report.Snapshots[len(report.Snapshots)-1]
+	if first.Round != -1 {
+		t.Errorf("First snapshot is round %d, expected the initial state", first.Round)
+	}
+	if got := snapshotValues(last); !slices.Equal(got, report.Final) {
+		t.Errorf("Last snapshot %v differs from the final array %v", got, report.Final)
+	}
+	if last.Inversions != 0 {
+		t.Errorf("Last snapshot has %d inversions", last.Inversions)
+	}
+}
+
+func snapshotValues[V any](s simulator.Snapshot[V]) []V {
+	return slices.Concat(s.Values...)
+}
+
+func TestSpaceTimeDiagram(t *testing.T) {
+	opts := testOptions(types.Config{Algorithm: types.Bitonic, NodeCount: 4})
+	opts.SVG = filepath.Join(t.TempDir(), "run.svg")
+	report, err := runner(blockSort(RunBitonic[int], nil), simulator.IntValues)(opts)
+	checkSorted(t, report, err, 4)
+
+	data, err := os.ReadFile(opts.SVG)
+	if err != nil {
+		t.Fatalf("No diagram written: %v", err)
+	}
+	svg := string(data)
+	if !strings.HasPrefix(strings.TrimSpace(svg), "<svg") || !strings.HasSuffix(strings.TrimSpace(svg), "</svg>") {
+		t.Fatalf("Diagram is not an SVG document:\n%s", svg)
+	}
+	// Bitonic on 4 nodes runs 3 rounds in which every node sends once.
+	if arrows := strings.Count(svg, "marker-end"); arrows != 12 {
+		t.Errorf("Diagram has %d message arrows, expected 12", arrows)
+	}
+}
+
+func TestBatchingCoalescesTreeMerge(t *testing.T) {
+	opts := testOptions(types.Config{Algorithm: types.TreeMerge, NodeCount: 31, BlockSize: 4})
+	opts.Transport.Batch = true
+	report, err := runner(treeSort(RunTreeMerge[int], InOrder), simulator.IntValues)(opts)
+	checkSorted(t, report, err, 124)
+
+	// A node whose children run ahead never waits, so its rounds share writes.
+	if stats := report.Transport; stats.Writes >= stats.Messages {
+		t.Errorf("Batching took %d writes for %d messages", stats.Writes, stats.Messages)
+	}
+}
+
+func TestBatchingAdaptiveOddEvenTerminates(t *testing.T) {
+	for _, termination := range []types.TerminationMode{types.MessageTermination, types.SharedTermination} {
+		t.Run(string(termination), func(t *testing.T) {
+			t.Parallel()
+			opts := testOptions(types.Config{Algorithm: types.OddEven, NodeCount: 8, Adaptive: true, Termination: termination})
+			opts.Transport.Batch = true
+			report, err := runner(oddEvenSort[int](), simulator.IntValues)(opts)
+			checkSorted(t, report, err, 8)
+		})
+	}
+}
@@ -3,45 +3,34 @@ package algorithms
 import (
 	"fmt"
 	"net"
-	"sort"
+	"slices"
 	"sync"
 
 	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/simulator"
 	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
 )
 
-type AlternativePayload struct {
-	Value int `json:"value"`
+type AlternativePayload[V any] struct {
+	Value V `json:"value"`
 }
 
-func RunAlternative(
-	n *types.Node[AlternativePayload],
-	engine *simulator.SimulatorEngine[AlternativePayload],
-	leftBuf, rightBuf *simulator.RoundBuffer[AlternativePayload],
-	sendFunc func(net.Conn, types.Message[AlternativePayload]) error,
+func RunAlternative[V any](
+	n *types.Node[AlternativePayload[V]],
+	engine *simulator.SimulatorEngine[AlternativePayload[V]],
+	sendFunc func(net.Conn, types.Message[AlternativePayload[V]]) error,
+	compare func(a, b V) int,
 	debug bool,
 ) {
+	left, right := n.Neighbor(types.Left), n.Neighbor(types.Right)
+
 	if debug {
-		fmt.Printf("[Algo] Node %d: Starting Alternative Sort (Value: %d)\n", n.ID, n.Value.Value)
+		fmt.Printf("[Algo] Node %d: Starting Alternative Sort (Value: %v)\n", n.ID, n.Value.Value)
 	}
 
 	for round := 1; round < n.TotalNode; round++ {
-		phaseIndex := (round + 1) % 3
-		var phaseStartID int
-		switch phaseIndex {
-		case 0:
-			phaseStartID = 2
-		case 1:
-			phaseStartID = 0
-		default:
-			phaseStartID = 1
-		}
-
-		isCenterNode := (n.ID >= phaseStartID) && ((n.ID-phaseStartID)%3 == 0)
-		isLeftWing := (n.ID+1 >= phaseStartID) && ((n.ID+1-phaseStartID)%3 == 0) && n.Position != types.Tail
-		isRightWing := (n.ID-1 >= phaseStartID) && ((n.ID-1-phaseStartID)%3 == 0) && n.Position != types.Head
+		isCenterNode, isLeftWing, isRightWing := alternativeRole(n.ID, round, n.Position)
 
-		msg := types.Message[AlternativePayload]{
+		msg := types.Message[AlternativePayload[V]]{
 			SenderID: n.ID,
 			Round:    round,
 			Body:     n.Value,
@@ -50,7 +39,7 @@ func RunAlternative(
 
 		if isCenterNode {
 
-			var leftValue, rightValue int
+			var leftValue, rightValue V
 			var hasLeftNeighbor, hasRightNeighbor bool
 			var wg sync.WaitGroup
 
@@ -59,7 +48,7 @@ func RunAlternative(
 				go func() {
 					defer wg.Done()
 
-					m := leftBuf.GetStepMessage(round)
+					m := left.Buffer.GetStepMessage(round)
 					leftValue = m.Body.Value
 					hasLeftNeighbor = true
 				}()
@@ -70,7 +59,7 @@ func RunAlternative(
 				go func() {
 					defer wg.Done()
 
-					m := rightBuf.GetStepMessage(round)
+					m := right.Buffer.GetStepMessage(round)
 					rightValue = m.Body.Value
 					hasRightNeighbor = true
 				}()
@@ -82,18 +71,18 @@ func RunAlternative(
 
 			if !hasLeftNeighbor && hasRightNeighbor {
 
-				if centerCandidate > rightCandidate {
+				if compare(centerCandidate, rightCandidate) > 0 {
 					centerCandidate, rightCandidate = rightCandidate, centerCandidate
 				}
 			} else if hasLeftNeighbor && !hasRightNeighbor {
 
-				if leftCandidate > centerCandidate {
+				if compare(leftCandidate, centerCandidate) > 0 {
 					leftCandidate, centerCandidate = centerCandidate, leftCandidate
 				}
 			} else if hasLeftNeighbor && hasRightNeighbor {
 
-				ordered := []int{leftValue, n.Value.Value, rightValue}
-				sort.Ints(ordered)
+				ordered := []V{leftValue, n.Value.Value, rightValue}
+				slices.SortStableFunc(ordered, compare)
 				leftCandidate, centerCandidate, rightCandidate = ordered[0], ordered[1], ordered[2]
 			}
 
@@ -102,28 +91,28 @@ func RunAlternative(
 			if hasLeftNeighbor {
 				msg.ReceiverID = n.ID - 1
 				msg.Body.Value = leftCandidate
-				_ = sendFunc(n.LeftConn, msg)
+				_ = sendFunc(left.Conn, msg)
 			}
 			if hasRightNeighbor {
 				msg.ReceiverID = n.ID + 1
 				msg.Body.Value = rightCandidate
-				_ = sendFunc(n.RightConn, msg)
+				_ = sendFunc(right.Conn, msg)
 			}
 
 		} else if isLeftWing {
 
 			msg.ReceiverID = n.ID + 1
-			_ = sendFunc(n.RightConn, msg)
+			_ = sendFunc(right.Conn, msg)
 
-			reply := rightBuf.GetStepMessage(round)
+			reply := right.Buffer.GetStepMessage(round)
 			n.Value.Value = reply.Body.Value
 
 		} else if isRightWing {
 
 			msg.ReceiverID = n.ID - 1
-			_ = sendFunc(n.LeftConn, msg)
+			_ = sendFunc(left.Conn, msg)
 
-			reply := leftBuf.GetStepMessage(round)
+			reply := left.Buffer.GetStepMessage(round)
 			n.Value.Value = reply.Body.Value
 		}
 
@@ -131,6 +120,148 @@ func RunAlternative(
 	}
 
 	if debug {
-		fmt.Printf("[Algo] Node %d: Alternative Sort Complete. Final: %d\n", n.ID, n.Value.Value)
+		fmt.Printf("[Algo] Node %d: Alternative Sort Complete. Final: %v\n", n.ID, n.Value.Value)
+	}
+}
+
+func RunAlternativeBlock[V any](
+	n *types.Node[BlockPayload[V]],
+	engine *simulator.SimulatorEngine[BlockPayload[V]],
+	sendFunc func(net.Conn, types.Message[BlockPayload[V]]) error,
+	compare func(a, b V) int,
+	debug bool,
+) {
+	left, right := n.Neighbor(types.Left), n.Neighbor(types.Right)
+
+	if debug {
+		fmt.Printf("[Algo] Node %d: Starting Alternative Block Sort (%d values)\n", n.ID, len(n.Value.Values))
+	}
+
+	k := len(n.Value.Values)
+
+	for round := 1; round < n.TotalNode; round++ {
+		isCenterNode, isLeftWing, isRightWing := alternativeRole(n.ID, round, n.Position)
+
+		msg := types.Message[BlockPayload[V]]{
+			SenderID: n.ID,
+			Round:    round,
+			Body:     n.Value,
+			Type:     types.MsgData,
+		}
+
+		if isCenterNode {
+			var leftValues, rightValues []V
+			var hasLeftNeighbor, hasRightNeighbor bool
+			var wg sync.WaitGroup
+
+			if n.Position != types.Head {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					leftValues = left.Buffer.GetStepMessage(round).Body.Values
+					hasLeftNeighbor = true
+				}()
+			}
+
+			if n.Position != types.Tail {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					rightValues = right.Buffer.GetStepMessage(round).Body.Values
+					hasRightNeighbor = true
+				}()
+			}
+
+			wg.Wait()
+
+			merged := mergeBlocks(mergeBlocks(leftValues, n.Value.Values, compare), rightValues, compare)
+			offset := 0
+			if hasLeftNeighbor {
+				offset = len(leftValues)
+			}
+			n.Value.Values = merged[offset : offset+k]
+
+			if hasLeftNeighbor {
+				msg.ReceiverID = n.ID - 1
+				msg.Body.Values = merged[:offset]
+				_ = sendFunc(left.Conn, msg)
+			}
+			if hasRightNeighbor {
+				msg.ReceiverID = n.ID + 1
+				msg.Body.Values = merged[offset+k:]
+				_ = sendFunc(right.Conn, msg)
+			}
+
+		} else if isLeftWing {
+
+			msg.ReceiverID = n.ID + 1
+			_ = sendFunc(right.Conn, msg)
+
+			n.Value.Values = right.Buffer.GetStepMessage(round).Body.Values
+
+		} else if isRightWing {
+
+			msg.ReceiverID = n.ID - 1
+			_ = sendFunc(left.Conn, msg)
+
+			n.Value.Values = left.Buffer.GetStepMessage(round).Body.Values
+		}
+
+		engine.IncrementClock(n)
+	}
+
+	if debug {
+		fmt.Printf("[Algo] Node %d: Alternative Block Sort Complete. Range: [%v, %v]\n", n.ID, n.Value.Values[0], n.Value.Values[k-1])
+	}
+}
+
+// alternativeRole places a node in the round's triplets: centers repeat
+// every third node starting from an offset that rotates with the round, and
+// the nodes on either side of a center act as its wings.
+func alternativeRole(id, round int, position types.Position) (isCenterNode, isLeftWing, isRightWing bool) {
+	var phaseStartID int
+	switch (round + 1) % 3 {
+	case 0:
+		phaseStartID = 2
+	case 1:
+		phaseStartID = 0
+	default:
+		phaseStartID = 1
+	}
+
+	isCenterNode = (id >= phaseStartID) && ((id-phaseStartID)%3 == 0)
+	isLeftWing = (id+1 >= phaseStartID) && ((id+1-phaseStartID)%3 == 0) && position != types.Tail
+	isRightWing = (id-1 >= phaseStartID) && ((id-1-phaseStartID)%3 == 0) && position != types.Head
+	return
+}
+
+// AlternativeState shows the role a node played in the round it just
+// finished for round snapshots: center, left wing, right wing or idle.
+// Rounds count from 1 while the node's clock counts from 0.
+func AlternativeState[T any](n *types.Node[T]) string {
+	switch center, leftWing, rightWing := alternativeRole(n.ID, n.Round+1, n.Position); {
+	case center:
+		return "C"
+	case leftWing:
+		return "LW"
+	case rightWing:
+		return "RW"
+	default:
+		return "-"
+	}
+}
+
+// AlternativeHighlight groups the nodes of each triplet around a center in
+// the round a node just finished, for -visualize.
+func AlternativeHighlight[T any](n *types.Node[T]) simulator.Highlight {
+	switch center, leftWing, rightWing := alternativeRole(n.ID, n.Round+1, n.Position); {
+	case center:
+		return simulator.Highlight{Group: n.ID + 1}
+	case leftWing:
+		return simulator.Highlight{Group: n.ID + 2}
+	case rightWing:
+		return simulator.Highlight{Group: n.ID}
+	default:
+		return simulator.Highlight{}
 	}
 }
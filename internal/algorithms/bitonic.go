@@ -0,0 +1,61 @@
+package algorithms
+
+import (
+	"fmt"
+	"math/bits"
+	"net"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/simulator"
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+// RunBitonic sorts on a hypercube of N = 2^d nodes. Stage i merges bitonic
+// sequences of 2^(i+1) nodes: in step j the node merge-splits with its
+// neighbor across dimension j, keeping the lower block if it has the lower
+// ID and the stage sorts its subcube ascending (bit i+1 of the ID is clear).
+// Every step uses a direct link, so the sort takes d(d+1)/2 rounds.
+func RunBitonic[V any](
+	n *types.Node[BlockPayload[V]],
+	engine *simulator.SimulatorEngine[BlockPayload[V]],
+	sendFunc func(net.Conn, types.Message[BlockPayload[V]]) error,
+	compare func(a, b V) int,
+	debug bool,
+) {
+	dims := bits.Len(uint(n.TotalNode)) - 1
+
+	if debug {
+		fmt.Printf("[Algo] Node %d: Starting Bitonic Sort (%d dimensions, %d values)\n", n.ID, dims, len(n.Value.Values))
+	}
+
+	round := 0
+	for stage := 0; stage < dims; stage++ {
+		ascending := n.ID&(1<<(stage+1)) == 0
+
+		for dim := stage; dim >= 0; dim-- {
+			neighbor := n.Neighbor(types.Dimension(dim))
+
+			msg := types.Message[BlockPayload[V]]{
+				SenderID:   n.ID,
+				ReceiverID: neighbor.ID,
+				Round:      round,
+				Body:       n.Value,
+				Type:       types.MsgData,
+			}
+			_ = sendFunc(neighbor.Conn, msg)
+			other := neighbor.Buffer.GetStepMessage(round).Body.Values
+
+			if (n.ID < neighbor.ID) == ascending {
+				n.Value.Values = splitLow(n.Value.Values, other, compare)
+			} else {
+				n.Value.Values = splitHigh(n.Value.Values, other, compare)
+			}
+
+			round++
+			engine.IncrementClock(n)
+		}
+	}
+
+	if debug {
+		fmt.Printf("[Algo] Node %d: Bitonic Sort Complete. Range: [%v, %v]\n", n.ID, n.Value.Values[0], n.Value.Values[len(n.Value.Values)-1])
+	}
+}
@@ -0,0 +1,36 @@
+package algorithms
+
+// BlockPayload carries the sorted block of values a node holds when every
+// node is responsible for more than one value.
+type BlockPayload[V any] struct {
+	Values []V `json:"values"`
+}
+
+func mergeBlocks[V any](a, b []V, compare func(a, b V) int) []V {
+	merged := make([]V, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if compare(a[i], b[j]) <= 0 {
+			merged = append(merged, a[i])
+			i++
+		} else {
+			merged = append(merged, b[j])
+			j++
+		}
+	}
+	merged = append(merged, a[i:]...)
+	return append(merged, b[j:]...)
+}
+
+// splitLow and splitHigh implement the merge-split step that replaces a
+// compare-exchange when nodes hold blocks: both partners merge the same two
+// blocks and keep the lower or upper k values respectively. The lower node
+// passes its own block first so that equal values keep their line order.
+func splitLow[V any](own, other []V, compare func(a, b V) int) []V {
+	return mergeBlocks(own, other, compare)[:len(own)]
+}
+
+func splitHigh[V any](own, other []V, compare func(a, b V) int) []V {
+	merged := mergeBlocks(other, own, compare)
+	return merged[len(merged)-len(own):]
+}
@@ -0,0 +1,47 @@
+package algorithms
+
+import (
+	"cmp"
+	"slices"
+	"testing"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+func TestMergeSplitPartnersAgree(t *testing.T) {
+	tests := []struct {
+		low, high []int
+	}{
+		{[]int{1, 4, 9}, []int{2, 3, 10}},
+		{[]int{5, 6, 7}, []int{1, 2, 3}},
+		{[]int{1, 2, 3}, []int{5, 6, 7}},
+		{[]int{2, 2, 2}, []int{2, 2, 2}},
+		{[]int{7}, []int{3}},
+	}
+	for _, tt := range tests {
+		lower := splitLow(tt.low, tt.high, cmp.Compare[int])
+		upper := splitHigh(tt.high, tt.low, cmp.Compare[int])
+
+		want := slices.Sorted(slices.Values(slices.Concat(tt.low, tt.high)))
+		if got := slices.Concat(lower, upper); !slices.Equal(got, want) {
+			t.Errorf("Merge-split of %v and %v gave %v | %v, expected %v", tt.low, tt.high, lower, upper, want)
+		}
+	}
+}
+
+func TestMergeSplitKeepsEqualKeysInLineOrder(t *testing.T) {
+	// Indexes 0-2 start on the lower node, 3-5 on the upper one.
+	low := []types.Record{{Key: 1, Index: 0}, {Key: 2, Index: 1}, {Key: 2, Index: 2}}
+	high := []types.Record{{Key: 1, Index: 3}, {Key: 2, Index: 4}, {Key: 3, Index: 5}}
+
+	lower := splitLow(low, high, types.CompareRecords)
+	upper := splitHigh(high, low, types.CompareRecords)
+
+	var indexes []int
+	for _, r := range slices.Concat(lower, upper) {
+		indexes = append(indexes, r.Index)
+	}
+	if want := []int{0, 3, 1, 2, 4, 5}; !slices.Equal(indexes, want) {
+		t.Errorf("Merge-split order %v, expected %v", indexes, want)
+	}
+}
@@ -3,53 +3,39 @@ package algorithms
 import (
 	"fmt"
 	"net"
+	"slices"
 
 	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/simulator"
 	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
 )
 
-type OddEvenPayload struct {
-	Value int `json:"value"`
+type OddEvenPayload[V any] struct {
+	Value V `json:"value"`
 }
 
-func RunOddEven(
-	n *types.Node[OddEvenPayload],
-	engine *simulator.SimulatorEngine[OddEvenPayload],
-	leftBuf, rightBuf *simulator.RoundBuffer[OddEvenPayload],
-	sendFunc func(net.Conn, types.Message[OddEvenPayload]) error,
+func RunOddEven[V any](
+	n *types.Node[OddEvenPayload[V]],
+	engine *simulator.SimulatorEngine[OddEvenPayload[V]],
+	sendFunc func(net.Conn, types.Message[OddEvenPayload[V]]) error,
+	compare func(a, b V) int,
 	debug bool,
 ) {
+	left, right := n.Neighbor(types.Left), n.Neighbor(types.Right)
+
 	if debug {
-		fmt.Printf("[Algo] Node %d: Starting Sort (Value: %d)\n", n.ID, n.Value.Value)
+		fmt.Printf("[Algo] Node %d: Starting Sort (Value: %v)\n", n.ID, n.Value.Value)
 	}
 
-	for round := 0; round < n.TotalNode; round++ {
-		isEvenRound := round%2 == 0
-		isEvenNodeID := n.ID%2 == 0
-
-		var partnerID int
-		var exchangeWithLeft bool
+	stable, stop := oddEvenTermination(n, engine)
+	defer stop()
 
-		if isEvenRound {
-			if isEvenNodeID {
-				partnerID = n.ID + 1
-				exchangeWithLeft = false
-			} else {
-				partnerID = n.ID - 1
-				exchangeWithLeft = true
-			}
-		} else {
-			if isEvenNodeID {
-				partnerID = n.ID - 1
-				exchangeWithLeft = true
-			} else {
-				partnerID = n.ID + 1
-				exchangeWithLeft = false
-			}
-		}
+	changedBefore := true
+	for round := 0; round < n.TotalNode; round++ {
+		partnerID, exchangeWithLeft := oddEvenPartner(n.ID, round)
+		changed := false
 
 		if partnerID >= 0 && partnerID < n.TotalNode {
-			msg := types.Message[OddEvenPayload]{
+			msg := types.Message[OddEvenPayload[V]]{
 				SenderID:   n.ID,
 				ReceiverID: partnerID,
 				Round:      round,
@@ -57,41 +43,151 @@ func RunOddEven(
 				Type:       types.MsgData,
 			}
 
-			conn := n.RightConn
+			partner := right
 			if exchangeWithLeft {
-				conn = n.LeftConn
+				partner = left
 			}
 
-			_ = sendFunc(conn, msg)
-
-			var neighborMsg types.Message[OddEvenPayload]
-			if exchangeWithLeft {
-				neighborMsg = leftBuf.GetStepMessage(round)
-			} else {
-				neighborMsg = rightBuf.GetStepMessage(round)
-			}
+			_ = sendFunc(partner.Conn, msg)
+			neighborMsg := partner.Buffer.GetStepMessage(round)
 
 			previousValue := n.Value.Value
 			neighborValue := neighborMsg.Body.Value
 
 			if exchangeWithLeft {
-				if n.Value.Value < neighborValue {
+				if compare(n.Value.Value, neighborValue) < 0 {
 					n.Value.Value = neighborValue
 				}
 			} else {
-				if n.Value.Value > neighborValue {
+				if compare(n.Value.Value, neighborValue) > 0 {
 					n.Value.Value = neighborValue
 				}
 			}
 
-			if debug && previousValue != n.Value.Value {
-				fmt.Printf("[Algo] Node %d: Swapped %d -> %d (Round %d)\n", n.ID, previousValue, n.Value.Value, round)
+			changed = compare(previousValue, n.Value.Value) != 0
+			if debug && changed {
+				fmt.Printf("[Algo] Node %d: Swapped %v -> %v (Round %d)\n", n.ID, previousValue, n.Value.Value, round)
+			}
+		}
+		engine.IncrementClock(n)
+
+		if stable(round, changed || changedBefore) {
+			if debug {
+				fmt.Printf("[Algo] Node %d: Stable after round %d, stopping early\n", n.ID, round)
+			}
+			break
+		}
+		changedBefore = changed
+	}
+
+	if debug {
+		fmt.Printf("[Algo] Node %d: Sort Complete. Final Value: %v\n", n.ID, n.Value.Value)
+	}
+}
+
+func RunOddEvenBlock[V any](
+	n *types.Node[BlockPayload[V]],
+	engine *simulator.SimulatorEngine[BlockPayload[V]],
+	sendFunc func(net.Conn, types.Message[BlockPayload[V]]) error,
+	compare func(a, b V) int,
+	debug bool,
+) {
+	left, right := n.Neighbor(types.Left), n.Neighbor(types.Right)
+
+	if debug {
+		fmt.Printf("[Algo] Node %d: Starting Block Sort (%d values)\n", n.ID, len(n.Value.Values))
+	}
+
+	stable, stop := oddEvenTermination(n, engine)
+	defer stop()
+
+	changedBefore := true
+	for round := 0; round < n.TotalNode; round++ {
+		partnerID, exchangeWithLeft := oddEvenPartner(n.ID, round)
+		changed := false
+
+		if partnerID >= 0 && partnerID < n.TotalNode {
+			previous := n.Value.Values
+			msg := types.Message[BlockPayload[V]]{
+				SenderID:   n.ID,
+				ReceiverID: partnerID,
+				Round:      round,
+				Body:       n.Value,
+				Type:       types.MsgData,
+			}
+
+			if exchangeWithLeft {
+				_ = sendFunc(left.Conn, msg)
+				neighborMsg := left.Buffer.GetStepMessage(round)
+				n.Value.Values = splitHigh(n.Value.Values, neighborMsg.Body.Values, compare)
+			} else {
+				_ = sendFunc(right.Conn, msg)
+				neighborMsg := right.Buffer.GetStepMessage(round)
+				n.Value.Values = splitLow(n.Value.Values, neighborMsg.Body.Values, compare)
 			}
+			changed = slices.CompareFunc(previous, n.Value.Values, compare) != 0
 		}
 		engine.IncrementClock(n)
+
+		if stable(round, changed || changedBefore) {
+			if debug {
+				fmt.Printf("[Algo] Node %d: Stable after round %d, stopping early\n", n.ID, round)
+			}
+			break
+		}
+		changedBefore = changed
 	}
 
 	if debug {
-		fmt.Printf("[Algo] Node %d: Sort Complete. Final Value: %d\n", n.ID, n.Value.Value)
+		fmt.Printf("[Algo] Node %d: Block Sort Complete. Range: [%v, %v]\n", n.ID, n.Value.Values[0], n.Value.Values[len(n.Value.Values)-1])
+	}
+}
+
+// oddEvenTermination returns the check RunOddEven makes after every round
+// when the engine runs adaptively: it votes and reports whether all nodes
+// agreed to stop. A node is stable once its values survived an even and an
+// odd round unchanged; when all nodes are, every adjacent pair was compared
+// without an exchange, so the line is sorted. The message-based detector
+// decides a round DefaultTerminationLag rounds later, which costs nothing
+// as a sorted line stays sorted.
+func oddEvenTermination[T any](n *types.Node[T], engine *simulator.SimulatorEngine[T]) (stable func(round int, active bool) bool, stop func()) {
+	switch {
+	case !engine.Adaptive:
+		return func(int, bool) bool { return false }, func() {}
+	case engine.Termination == types.SharedTermination:
+		return func(round int, active bool) bool {
+			if active {
+				engine.ResetStability(round)
+			} else {
+				engine.SignalStable(round)
+			}
+			simulator.FlushLinks(n)
+			return engine.CheckTermination(round)
+		}, func() {}
+	default:
+		detector := simulator.NewTerminationDetector(n, engine.Topology)
+		return func(round int, active bool) bool {
+			detector.Vote(round, !active)
+			return detector.Terminated(round - detector.Lag)
+		}, detector.Close
+	}
+}
+
+// oddEvenPartner returns the neighbor a node compare-exchanges with in the
+// given round: even nodes pair rightwards in even rounds, leftwards in odd.
+func oddEvenPartner(id, round int) (partnerID int, exchangeWithLeft bool) {
+	if (round%2 == 0) == (id%2 == 0) {
+		return id + 1, false
+	}
+	return id - 1, true
+}
+
+// OddEvenHighlight groups a node with the partner it compare-exchanged with
+// in the round it just finished, for -visualize.
+func OddEvenHighlight[T any](n *types.Node[T]) simulator.Highlight {
+	partnerID, _ := oddEvenPartner(n.ID, n.Round)
+	if partnerID < 0 || partnerID >= n.TotalNode {
+		return simulator.Highlight{}
 	}
+	return simulator.Highlight{Group: min(n.ID, partnerID) + 1}
 }
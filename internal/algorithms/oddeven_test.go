@@ -0,0 +1,40 @@
+package algorithms
+
+import (
+	"testing"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/simulator"
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+func TestAdaptiveOddEvenStopsEarlyOnSortedInput(t *testing.T) {
+	const nodes = 16
+	for _, termination := range []types.TerminationMode{types.MessageTermination, types.SharedTermination} {
+		t.Run(string(termination), func(t *testing.T) {
+			t.Parallel()
+			opts := testOptions(types.Config{
+				Algorithm:   types.OddEven,
+				NodeCount:   nodes,
+				InputType:   types.Sorted,
+				Adaptive:    true,
+				Termination: termination,
+			})
+			report, err := runner(oddEvenSort[int](), simulator.IntValues)(opts)
+			checkSorted(t, report, err, nodes)
+
+			// Two rounds to notice nothing changes, plus the detector's lag.
+			if limit := 2 + simulator.DefaultTerminationLag + 1; report.Rounds > limit {
+				t.Errorf("Sorted input took %d of %d rounds, expected at most %d", report.Rounds, nodes, limit)
+			}
+		})
+	}
+}
+
+func TestAdaptiveOddEvenBlockSortsRandomInput(t *testing.T) {
+	opts := testOptions(types.Config{Algorithm: types.OddEven, NodeCount: 8, BlockSize: 4, Adaptive: true})
+	report, err := runner(blockSort(RunOddEvenBlock[int], nil), simulator.IntValues)(opts)
+	checkSorted(t, report, err, 32)
+	if report.Rounds > 8 {
+		t.Errorf("Adaptive run took %d rounds, more than the %d of a full run", report.Rounds, 8)
+	}
+}
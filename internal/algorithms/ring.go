@@ -0,0 +1,129 @@
+package algorithms
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/simulator"
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+// RingPayload is the block a ring node holds, or the values it passes on.
+// While values are routed to the node they belong to, Ranks holds the
+// global rank of each of them.
+type RingPayload[V any] struct {
+	Values []V   `json:"values"`
+	Ranks  []int `json:"ranks,omitempty"`
+}
+
+// RunRingRank sorts on a bidirectional ring by rank counting. In the first
+// N/2 rounds every block travels around the ring in both directions at
+// once, over the wrap link too, and each node counts how many values of
+// every passing block go before each of its own: that count is the value's
+// global rank. Equal values are ordered by their initial position, rank*k
+// plus their index in the block, so the sort is stable. In the next N/2
+// rounds every value travels the shorter way around the ring to the node
+// of rank rank/k. Both phases rely on the wrap link to halve the distance
+// a value covers on the line.
+func RunRingRank[V any](
+	n *types.Node[RingPayload[V]],
+	engine *simulator.SimulatorEngine[RingPayload[V]],
+	sendFunc func(net.Conn, types.Message[RingPayload[V]]) error,
+	compare func(a, b V) int,
+	debug bool,
+) {
+	left, right := n.Neighbor(types.Left), n.Neighbor(types.Right)
+
+	if debug {
+		fmt.Printf("[Algo] Node %d: Starting Ring Rank Sort at rank %d (%d values)\n", n.ID, n.Rank, len(n.Value.Values))
+	}
+
+	total := n.TotalNode
+	own := n.Value.Values
+	k := len(own)
+
+	send := func(nb *types.Neighbor[RingPayload[V]], round int, body RingPayload[V]) {
+		_ = sendFunc(nb.Conn, types.Message[RingPayload[V]]{
+			SenderID:   n.ID,
+			ReceiverID: nb.ID,
+			Round:      round,
+			Body:       body,
+			Type:       types.MsgData,
+		})
+	}
+
+	// count adds to ranks the values of the block that started at rank
+	// origin which go before each of the node's own values.
+	ranks := make([]int, k)
+	count := func(block []V, origin int) {
+		for j, v := range own {
+			for i, w := range block {
+				if c := compare(w, v); c < 0 || c == 0 && origin*k+i < n.Rank*k+j {
+					ranks[j]++
+				}
+			}
+		}
+	}
+	count(own, n.Rank)
+
+	// Counting: blocks go right for N/2 rounds and left for (N-1)/2, so
+	// every node sees each of the other N-1 blocks exactly once.
+	round := 0
+	toRight, toLeft := own, own
+	for ; round < total/2; round++ {
+		leftward := round < (total-1)/2
+		send(right, round, RingPayload[V]{Values: toRight})
+		if leftward {
+			send(left, round, RingPayload[V]{Values: toLeft})
+		}
+
+		toRight = left.Buffer.GetStepMessage(round).Body.Values
+		count(toRight, (n.Rank-round-1+total)%total)
+		if leftward {
+			toLeft = right.Buffer.GetStepMessage(round).Body.Values
+			count(toLeft, (n.Rank+round+1)%total)
+		}
+		engine.IncrementClock(n)
+	}
+
+	// Routing: a value of rank r belongs at slot r%k of the node of rank
+	// r/k, at most N/2 hops away one way or the other.
+	sorted := make([]V, k)
+	var goRight, goLeft RingPayload[V]
+	route := func(v V, rank int, into *RingPayload[V]) {
+		if rank/k == n.Rank {
+			sorted[rank%k] = v
+			return
+		}
+		into.Values = append(into.Values, v)
+		into.Ranks = append(into.Ranks, rank)
+	}
+	for j, v := range own {
+		if (ranks[j]/k-n.Rank+total)%total <= total/2 {
+			route(v, ranks[j], &goRight)
+		} else {
+			route(v, ranks[j], &goLeft)
+		}
+	}
+	for range total / 2 {
+		send(right, round, goRight)
+		send(left, round, goLeft)
+
+		fromLeft := left.Buffer.GetStepMessage(round).Body
+		fromRight := right.Buffer.GetStepMessage(round).Body
+		goRight, goLeft = RingPayload[V]{}, RingPayload[V]{}
+		for i, v := range fromLeft.Values {
+			route(v, fromLeft.Ranks[i], &goRight)
+		}
+		for i, v := range fromRight.Values {
+			route(v, fromRight.Ranks[i], &goLeft)
+		}
+		engine.IncrementClock(n)
+		round++
+	}
+	n.Value.Values = sorted
+
+	if debug {
+		fmt.Printf("[Algo] Node %d: Ring Rank Sort Complete. Range: [%v, %v]\n", n.ID, n.Value.Values[0], n.Value.Values[k-1])
+	}
+}
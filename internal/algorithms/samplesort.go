@@ -0,0 +1,124 @@
+package algorithms
+
+import (
+	"fmt"
+	"net"
+	"slices"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/simulator"
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+// RunSampleSort sorts on a fully connected network by parallel sorting by
+// regular sampling in three rounds:
+//
+//  1. every node sends N-1 evenly spaced samples of its sorted block to
+//     node 0;
+//  2. node 0 sorts the samples and broadcasts N-1 evenly spaced splitters;
+//  3. every node cuts its block at the splitters and sends bucket j to node
+//     j, which sorts what it receives.
+//
+// A value goes to the first bucket whose splitter is not smaller, so equal
+// values always share a bucket. Buckets are uneven: with distinct values a
+// node ends up with at most about 2k of them, with many equal values more.
+func RunSampleSort[V any](
+	n *types.Node[BlockPayload[V]],
+	engine *simulator.SimulatorEngine[BlockPayload[V]],
+	sendFunc func(net.Conn, types.Message[BlockPayload[V]]) error,
+	compare func(a, b V) int,
+	debug bool,
+) {
+	const coordinator = 0
+
+	if debug {
+		fmt.Printf("[Algo] Node %d: Starting Sample Sort (%d values)\n", n.ID, len(n.Value.Values))
+	}
+
+	send := func(to, round int, values []V) {
+		msg := types.Message[BlockPayload[V]]{
+			SenderID:   n.ID,
+			ReceiverID: to,
+			Round:      round,
+			Body:       BlockPayload[V]{Values: values},
+			Type:       types.MsgData,
+		}
+		_ = sendFunc(n.Neighbor(types.Peer(to)).Conn, msg)
+	}
+	receive := func(from, round int) []V {
+		return n.Neighbor(types.Peer(from)).Buffer.GetStepMessage(round).Body.Values
+	}
+
+	samples := regularSample(n.Value.Values, n.TotalNode-1)
+	if n.ID != coordinator {
+		send(coordinator, 0, samples)
+	} else {
+		for peer := range n.TotalNode {
+			if peer != coordinator {
+				samples = append(samples, receive(peer, 0)...)
+			}
+		}
+	}
+	engine.IncrementClock(n)
+
+	var splitters []V
+	if n.ID == coordinator {
+		slices.SortStableFunc(samples, compare)
+		for j := 1; j < n.TotalNode; j++ {
+			splitters = append(splitters, samples[j*len(samples)/n.TotalNode])
+		}
+		for peer := range n.TotalNode {
+			if peer != coordinator {
+				send(peer, 1, splitters)
+			}
+		}
+	} else {
+		splitters = receive(coordinator, 1)
+	}
+	engine.IncrementClock(n)
+
+	buckets := make([][]V, n.TotalNode)
+	rest := n.Value.Values
+	for j, splitter := range splitters {
+		cut, _ := slices.BinarySearchFunc(rest, splitter, func(v, s V) int {
+			if compare(v, s) <= 0 {
+				return -1
+			}
+			return 1
+		})
+		buckets[j], rest = rest[:cut], rest[cut:]
+	}
+	buckets[n.TotalNode-1] = rest
+
+	for peer := range n.TotalNode {
+		if peer != n.ID {
+			send(peer, 2, buckets[peer])
+		}
+	}
+	// Concatenating in sender order before a stable sort keeps equal values
+	// in their initial order.
+	received := make([][]V, n.TotalNode)
+	for peer := range n.TotalNode {
+		if peer == n.ID {
+			received[peer] = buckets[n.ID]
+		} else {
+			received[peer] = receive(peer, 2)
+		}
+	}
+	n.Value.Values = slices.Concat(received...)
+	slices.SortStableFunc(n.Value.Values, compare)
+	engine.IncrementClock(n)
+
+	if debug {
+		fmt.Printf("[Algo] Node %d: Sample Sort Complete. Bucket of %d values\n", n.ID, len(n.Value.Values))
+	}
+}
+
+// regularSample picks up to count evenly spaced values from a sorted block.
+func regularSample[V any](values []V, count int) []V {
+	count = min(count, len(values))
+	samples := make([]V, 0, count)
+	for i := range count {
+		samples = append(samples, values[i*len(values)/count])
+	}
+	return samples
+}
@@ -1,48 +1,57 @@
 package algorithms
 
 import (
+	"cmp"
 	"fmt"
-	"math"
 	"net"
+	"slices"
 
 	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/simulator"
 	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
 )
 
-type SasakiElement struct {
-	Value    int  `json:"value"`
+// SasakiElement is one value in an L or R slot. Bound marks the sentinels
+// the head and tail start with: -1 sorts below every value, +1 above.
+// Tag breaks ties between equal values; elements that should compare equal
+// leave it at zero.
+type SasakiElement[V any] struct {
+	Value    V    `json:"value"`
 	IsMarked bool `json:"is_marked"`
+	Bound    int  `json:"bound,omitempty"`
+	Tag      int  `json:"tag,omitempty"`
 }
 
-type SasakiPayload struct {
-	LValue SasakiElement `json:"l_value"`
-	RValue SasakiElement `json:"r_value"`
-	Area   int           `json:"area"`
+type SasakiPayload[V any] struct {
+	LValue SasakiElement[V] `json:"l_value"`
+	RValue SasakiElement[V] `json:"r_value"`
+	Area   int              `json:"area"`
 
-	Value int `json:"value"`
+	Value V `json:"value"`
 }
 
-func RunSasaki(
-	n *types.Node[SasakiPayload],
-	engine *simulator.SimulatorEngine[SasakiPayload],
-	leftBuf, rightBuf *simulator.RoundBuffer[SasakiPayload],
-	sendFunc func(net.Conn, types.Message[SasakiPayload]) error,
+func RunSasaki[V any](
+	n *types.Node[SasakiPayload[V]],
+	engine *simulator.SimulatorEngine[SasakiPayload[V]],
+	sendFunc func(net.Conn, types.Message[SasakiPayload[V]]) error,
+	compare func(a, b V) int,
 	debug bool,
 ) {
+	left, right := n.Neighbor(types.Left), n.Neighbor(types.Right)
+
 	initialValue := n.Value.Value
 
 	switch n.Position {
 	case types.Head:
-		n.Value.LValue = SasakiElement{Value: math.MinInt32, IsMarked: false}
-		n.Value.RValue = SasakiElement{Value: initialValue, IsMarked: true}
+		n.Value.LValue = SasakiElement[V]{Bound: -1, IsMarked: false}
+		n.Value.RValue = SasakiElement[V]{Value: initialValue, IsMarked: true}
 		n.Value.Area = -1
 	case types.Tail:
-		n.Value.LValue = SasakiElement{Value: initialValue, IsMarked: true}
-		n.Value.RValue = SasakiElement{Value: math.MaxInt32, IsMarked: false}
+		n.Value.LValue = SasakiElement[V]{Value: initialValue, IsMarked: true}
+		n.Value.RValue = SasakiElement[V]{Bound: 1, IsMarked: false}
 		n.Value.Area = 0
 	default:
-		n.Value.LValue = SasakiElement{Value: initialValue, IsMarked: false}
-		n.Value.RValue = SasakiElement{Value: initialValue, IsMarked: false}
+		n.Value.LValue = SasakiElement[V]{Value: initialValue, IsMarked: false}
+		n.Value.RValue = SasakiElement[V]{Value: initialValue, IsMarked: false}
 		n.Value.Area = 0
 	}
 
@@ -51,7 +60,7 @@ func RunSasaki(
 	}
 
 	for round := 1; round < n.TotalNode; round++ {
-		msg := types.Message[SasakiPayload]{
+		msg := types.Message[SasakiPayload[V]]{
 			SenderID: n.ID,
 			Round:    round,
 			Type:     types.MsgData,
@@ -60,23 +69,23 @@ func RunSasaki(
 		if n.Position != types.Tail {
 			msg.ReceiverID = n.ID + 1
 			msg.Body.RValue = n.Value.RValue
-			msg.Body.LValue = SasakiElement{}
-			_ = sendFunc(n.RightConn, msg)
+			msg.Body.LValue = SasakiElement[V]{}
+			_ = sendFunc(right.Conn, msg)
 		}
 
 		if n.Position != types.Head {
 			msg.ReceiverID = n.ID - 1
 			msg.Body.LValue = n.Value.LValue
-			msg.Body.RValue = SasakiElement{}
-			_ = sendFunc(n.LeftConn, msg)
+			msg.Body.RValue = SasakiElement[V]{}
+			_ = sendFunc(left.Conn, msg)
 		}
 
-		leftMsg, rightMsg := simulator.WaitForNeighbors(n, round, leftBuf, rightBuf)
+		leftMsg, rightMsg := simulator.WaitForNeighbors(n, round)
 
 		if n.Position != types.Head && leftMsg != nil {
 			leftIncomingR := leftMsg.Body.RValue
 
-			if leftIncomingR.Value > n.Value.LValue.Value {
+			if compareElements(leftIncomingR, n.Value.LValue, compare) > 0 {
 
 				if leftIncomingR.IsMarked {
 					n.Value.Area--
@@ -91,27 +100,340 @@ func RunSasaki(
 
 		if n.Position != types.Tail && rightMsg != nil {
 			rightIncomingL := rightMsg.Body.LValue
-			if rightIncomingL.Value < n.Value.RValue.Value {
+			if compareElements(rightIncomingL, n.Value.RValue, compare) < 0 {
 				n.Value.RValue = rightIncomingL
 			}
 		}
 
-		if n.Value.LValue.Value > n.Value.RValue.Value {
+		if compareElements(n.Value.LValue, n.Value.RValue, compare) > 0 {
 			temp := n.Value.LValue
 			n.Value.LValue = n.Value.RValue
 			n.Value.RValue = temp
 		}
 
+		// The node's output so far, final after the last round.
+		if n.Value.Area == -1 {
+			n.Value.Value = n.Value.RValue.Value
+		} else {
+			n.Value.Value = n.Value.LValue.Value
+		}
+
+		engine.IncrementClock(n)
+	}
+
+	if debug {
+		fmt.Printf("[Algo] Node %d: Sasaki Complete. Final: %v (Area: %d)\n", n.ID, n.Value.Value, n.Value.Area)
+	}
+}
+
+type SasakiBlockPayload[V any] struct {
+	LBlock []SasakiElement[V] `json:"l_block"`
+	RBlock []SasakiElement[V] `json:"r_block"`
+	Area   int                `json:"area"`
+
+	Values []V `json:"values"`
+}
+
+// RunSasakiBlock generalizes Sasaki's algorithm to blocks of k values: L and
+// R become blocks, every compare-exchange becomes a merge-split, and Area
+// counts marked values instead of marked elements. After N-1 rounds the L/R
+// slots of the whole line are sorted, but a node's k output values can start
+// up to k slots into a neighbor, so one extra round exchanges the slots with
+// both neighbors before each node picks its values.
+//
+// Unlike single values, blocks need every element to be distinct: the final
+// pick steps over both copies of an unmarked value at once, which only works
+// if nothing sorts between them. Each element is therefore tagged with a hash
+// of the slot it started in, which keeps equal values apart without putting
+// them back in their initial order. That is deliberate: the slot order is
+// information textbook block Sasaki does not use, so this variant leaves
+// ties arbitrary and shows up as unstable in the stability report, while
+// RunStableSasakiBlock opts into the slot order.
+func RunSasakiBlock[V any](
+	n *types.Node[SasakiBlockPayload[V]],
+	engine *simulator.SimulatorEngine[SasakiBlockPayload[V]],
+	sendFunc func(net.Conn, types.Message[SasakiBlockPayload[V]]) error,
+	compare func(a, b V) int,
+	debug bool,
+) {
+	runSasakiBlock(n, engine, sendFunc, compare, debug, false)
+}
+
+// RunStableSasakiBlock is RunSasakiBlock with elements tagged by the slot
+// they started in, so equal values keep their initial order.
+func RunStableSasakiBlock[V any](
+	n *types.Node[SasakiBlockPayload[V]],
+	engine *simulator.SimulatorEngine[SasakiBlockPayload[V]],
+	sendFunc func(net.Conn, types.Message[SasakiBlockPayload[V]]) error,
+	compare func(a, b V) int,
+	debug bool,
+) {
+	runSasakiBlock(n, engine, sendFunc, compare, debug, true)
+}
+
+func runSasakiBlock[V any](
+	n *types.Node[SasakiBlockPayload[V]],
+	engine *simulator.SimulatorEngine[SasakiBlockPayload[V]],
+	sendFunc func(net.Conn, types.Message[SasakiBlockPayload[V]]) error,
+	compare func(a, b V) int,
+	debug bool,
+	stable bool,
+) {
+	left, right := n.Neighbor(types.Left), n.Neighbor(types.Right)
+
+	initialValues := n.Value.Values
+	k := len(initialValues)
+
+	// A lone node is both ends of the line, so realSlots would cut both of
+	// its k-slices away. Its block is the whole input; it only sorts it.
+	if n.TotalNode == 1 {
+		n.Value.Values = slices.Clone(initialValues)
+		slices.SortStableFunc(n.Value.Values, compare)
+		engine.IncrementClock(n)
+		return
+	}
+
+	sentinels := func(bound int) []SasakiElement[V] {
+		block := make([]SasakiElement[V], k)
+		for i := range block {
+			block[i] = SasakiElement[V]{Bound: bound}
+		}
+		return block
+	}
+	fromValues := func(marked bool) []SasakiElement[V] {
+		block := make([]SasakiElement[V], k)
+		for i, v := range initialValues {
+			tag := n.ID*k + i
+			if !stable {
+				tag = scrambleTag(tag)
+			}
+			block[i] = SasakiElement[V]{Value: v, IsMarked: marked, Tag: tag}
+		}
+		// Equal values have to be in tag order for the merges.
+		slices.SortFunc(block, func(a, b SasakiElement[V]) int { return compareElements(a, b, compare) })
+		return block
+	}
+
+	switch n.Position {
+	case types.Head:
+		n.Value.LBlock = sentinels(-1)
+		n.Value.RBlock = fromValues(true)
+		n.Value.Area = -k
+	case types.Tail:
+		n.Value.LBlock = fromValues(true)
+		n.Value.RBlock = sentinels(1)
+		n.Value.Area = 0
+	default:
+		n.Value.LBlock = fromValues(false)
+		n.Value.RBlock = fromValues(false)
+		n.Value.Area = 0
+	}
+
+	if debug {
+		fmt.Printf("[Algo] Node %d: Init Sasaki Block Area=%d k=%d\n", n.ID, n.Value.Area, k)
+	}
+
+	for round := 1; round < n.TotalNode; round++ {
+		msg := types.Message[SasakiBlockPayload[V]]{
+			SenderID: n.ID,
+			Round:    round,
+			Type:     types.MsgData,
+		}
+
+		if n.Position != types.Tail {
+			msg.ReceiverID = n.ID + 1
+			msg.Body = SasakiBlockPayload[V]{RBlock: n.Value.RBlock}
+			_ = sendFunc(right.Conn, msg)
+		}
+
+		if n.Position != types.Head {
+			msg.ReceiverID = n.ID - 1
+			msg.Body = SasakiBlockPayload[V]{LBlock: n.Value.LBlock}
+			_ = sendFunc(left.Conn, msg)
+		}
+
+		leftMsg, rightMsg := simulator.WaitForNeighbors(n, round)
+
+		if n.Position != types.Head && leftMsg != nil {
+			merged := mergeElements(n.Value.LBlock, leftMsg.Body.RBlock, compare)
+			kept := merged[k:]
+			n.Value.Area += countMarked(n.Value.LBlock) - countMarked(kept)
+			n.Value.LBlock = kept
+		}
+
+		if n.Position != types.Tail && rightMsg != nil {
+			n.Value.RBlock = mergeElements(n.Value.RBlock, rightMsg.Body.LBlock, compare)[:k]
+		}
+
+		merged := mergeElements(n.Value.LBlock, n.Value.RBlock, compare)
+		n.Value.LBlock, n.Value.RBlock = merged[:k], merged[k:]
+
 		engine.IncrementClock(n)
 	}
 
-	if n.Value.Area == -1 {
-		n.Value.Value = n.Value.RValue.Value
-	} else {
-		n.Value.Value = n.Value.LValue.Value
+	extractRound := max(n.TotalNode, 1)
+	msg := types.Message[SasakiBlockPayload[V]]{
+		SenderID: n.ID,
+		Round:    extractRound,
+		Type:     types.MsgData,
+		Body:     SasakiBlockPayload[V]{LBlock: n.Value.LBlock, RBlock: n.Value.RBlock, Area: n.Value.Area},
+	}
+	if n.Position != types.Tail {
+		msg.ReceiverID = n.ID + 1
+		_ = sendFunc(right.Conn, msg)
+	}
+	if n.Position != types.Head {
+		msg.ReceiverID = n.ID - 1
+		_ = sendFunc(left.Conn, msg)
 	}
+	leftMsg, rightMsg := simulator.WaitForNeighbors(n, extractRound)
+
+	realSlots := func(p SasakiBlockPayload[V], id int) []SasakiElement[V] {
+		slots := mergeElements(p.LBlock, p.RBlock, compare)
+		if id == 0 {
+			slots = slots[k:]
+		}
+		if id == n.TotalNode-1 {
+			slots = slots[:len(slots)-k]
+		}
+		return slots
+	}
+
+	windowStart, windowArea := n.ID, n.Value.Area
+	var window []SasakiElement[V]
+	if leftMsg != nil {
+		windowStart, windowArea = n.ID-1, leftMsg.Body.Area
+		window = append(window, realSlots(leftMsg.Body, n.ID-1)...)
+	}
+	window = append(window, realSlots(n.Value, n.ID)...)
+	if rightMsg != nil {
+		window = append(window, realSlots(rightMsg.Body, n.ID+1)...)
+	}
+
+	n.Value.Values = pickSasakiBlock(window, windowStart, windowArea, n.ID, k)
+	engine.IncrementClock(n)
 
 	if debug {
-		fmt.Printf("[Algo] Node %d: Sasaki Complete. Final: %d (Area: %d)\n", n.ID, n.Value.Value, n.Value.Area)
+		fmt.Printf("[Algo] Node %d: Sasaki Block Complete. Range: [%v, %v] (Area: %d)\n", n.ID, n.Value.Values[0], n.Value.Values[k-1], n.Value.Area)
+	}
+}
+
+// pickSasakiBlock walks the sorted real slots starting at node windowStart
+// and returns the values of original ranks [id*k, id*k+k). Every unmarked
+// value occupies two consecutive slots and every marked one a single slot;
+// the marked values left of the window (Area+k of them) tell whether the
+// first slot is the second copy of a value that started in an earlier node.
+func pickSasakiBlock[V any](window []SasakiElement[V], windowStart, windowArea, id, k int) []V {
+	slotsBefore, markedBefore := 0, 0
+	if windowStart > 0 {
+		slotsBefore = 2*windowStart*k - k
+		markedBefore = windowArea + k
+	}
+	straddle := ((slotsBefore-markedBefore)%2 + 2) % 2
+	rank := (slotsBefore-markedBefore+straddle)/2 + markedBefore
+
+	values := make([]V, 0, k)
+	take := func(rank int, value V) {
+		if rank >= id*k && rank < id*k+k {
+			values = append(values, value)
+		}
+	}
+
+	pos := 0
+	if straddle == 1 && len(window) > 0 {
+		take(rank-1, window[0].Value)
+		pos = 1
+	}
+	for pos < len(window) {
+		take(rank, window[pos].Value)
+		rank++
+		if window[pos].IsMarked {
+			pos++
+		} else {
+			pos += 2
+		}
+	}
+	return values
+}
+
+// scrambleTag maps a slot to a distinct tag in no particular order, a
+// multiplicative hash that is a bijection on 32-bit slots.
+func scrambleTag(slot int) int {
+	return int(uint32(slot) * 0x9E3779B1)
+}
+
+// compareElements orders sentinels below or above every value, compares
+// real values with the caller's comparator and equal values by tag.
+func compareElements[V any](a, b SasakiElement[V], compare func(a, b V) int) int {
+	if a.Bound != 0 || b.Bound != 0 {
+		return cmp.Compare(a.Bound, b.Bound)
+	}
+	if c := compare(a.Value, b.Value); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Tag, b.Tag)
+}
+
+func mergeElements[V any](a, b []SasakiElement[V], compare func(a, b V) int) []SasakiElement[V] {
+	merged := make([]SasakiElement[V], 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if compareElements(b[j], a[i], compare) >= 0 {
+			merged = append(merged, a[i])
+			i++
+		} else {
+			merged = append(merged, b[j])
+			j++
+		}
+	}
+	merged = append(merged, a[i:]...)
+	return append(merged, b[j:]...)
+}
+
+func countMarked[V any](block []SasakiElement[V]) int {
+	count := 0
+	for _, e := range block {
+		if e.IsMarked {
+			count++
+		}
+	}
+	return count
+}
+
+// SasakiState shows a node's L and R slots and Area for round snapshots;
+// marked values carry a *, the sentinels show as -inf and +inf.
+func SasakiState[V any](n *types.Node[SasakiPayload[V]]) string {
+	return fmt.Sprintf("L=%s R=%s A=%d", n.Value.LValue, n.Value.RValue, n.Value.Area)
+}
+
+// SasakiBlockState is SasakiState for blocks.
+func SasakiBlockState[V any](n *types.Node[SasakiBlockPayload[V]]) string {
+	return fmt.Sprintf("L=%v R=%v A=%d", n.Value.LBlock, n.Value.RBlock, n.Value.Area)
+}
+
+func (e SasakiElement[V]) String() string {
+	switch {
+	case e.Bound < 0:
+		return "-inf"
+	case e.Bound > 0:
+		return "+inf"
+	case e.IsMarked:
+		return fmt.Sprintf("%v*", e.Value)
+	default:
+		return fmt.Sprint(e.Value)
+	}
+}
+
+// SasakiHighlight marks nodes holding a marked value, for -visualize.
+func SasakiHighlight[V any](n *types.Node[SasakiPayload[V]]) simulator.Highlight {
+	return simulator.Highlight{Marked: n.Value.LValue.IsMarked || n.Value.RValue.IsMarked}
+}
+
+// SasakiBlockHighlight is SasakiHighlight for blocks.
+func SasakiBlockHighlight[V any](n *types.Node[SasakiBlockPayload[V]]) simulator.Highlight {
+	marked := false
+	for _, e := range slices.Concat(n.Value.LBlock, n.Value.RBlock) {
+		marked = marked || e.IsMarked
 	}
+	return simulator.Highlight{Marked: marked}
 }
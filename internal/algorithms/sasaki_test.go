@@ -0,0 +1,89 @@
+package algorithms
+
+import (
+	"cmp"
+	"slices"
+	"testing"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/simulator"
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+func TestCompareElementsSentinels(t *testing.T) {
+	low := SasakiElement[int]{Bound: -1}
+	high := SasakiElement[int]{Bound: 1}
+	value := SasakiElement[int]{Value: -1 << 31}
+	if compareElements(low, value, cmp.Compare[int]) >= 0 || compareElements(value, high, cmp.Compare[int]) >= 0 {
+		t.Error("Sentinels should sort below and above every value")
+	}
+}
+
+func TestCompareElementsTies(t *testing.T) {
+	a := SasakiElement[int]{Value: 4, Tag: 9}
+	b := SasakiElement[int]{Value: 4, Tag: 2, IsMarked: true}
+	if c := compareElements(a, b, cmp.Compare[int]); c <= 0 {
+		t.Errorf("Equal values should be ordered by tag, got %d", c)
+	}
+	a.Tag, b.Tag = 0, 0
+	if c := compareElements(a, b, cmp.Compare[int]); c != 0 {
+		t.Errorf("Untagged equal values should compare equal, got %d", c)
+	}
+}
+
+func TestScrambleTagIsDistinctAndUnordered(t *testing.T) {
+	const slots = 4096
+	seen := make(map[int]bool, slots)
+	ascending := 0
+	for slot := range slots {
+		tag := scrambleTag(slot)
+		if seen[tag] {
+			t.Fatalf("Slot %d reuses tag %d", slot, tag)
+		}
+		seen[tag] = true
+		if slot > 0 && tag > scrambleTag(slot-1) {
+			ascending++
+		}
+	}
+	// Tags following slot order would make block Sasaki stable again.
+	if ascending == slots-1 {
+		t.Error("Tags follow the slot order")
+	}
+}
+
+func TestPickSasakiBlock(t *testing.T) {
+	// Three nodes with k=2: the head starts with 0 and 5 and the tail with
+	// 1 and 4, which are marked, and the middle node with 2 and 3, which
+	// take two slots each. The sorted real slots of the line are therefore
+	// 0* 1* | 2 2 3 3 | 4* 5*, split by node.
+	slot := func(v int, marked bool) SasakiElement[int] {
+		return SasakiElement[int]{Value: v, IsMarked: marked}
+	}
+	line := []SasakiElement[int]{
+		slot(0, true), slot(1, true),
+		slot(2, false), slot(2, false), slot(3, false), slot(3, false),
+		slot(4, true), slot(5, true),
+	}
+
+	tests := []struct {
+		id, windowStart, windowArea int
+		window                      []SasakiElement[int]
+		want                        []int
+	}{
+		{0, 0, -2, line[:6], []int{0, 1}},
+		{1, 0, -2, line, []int{2, 3}},
+		// Node 1's Area is 0: both marked values left of the window lie in
+		// the head's slots.
+		{2, 1, 0, line[2:], []int{4, 5}},
+	}
+	for _, tt := range tests {
+		if got := pickSasakiBlock(tt.window, tt.windowStart, tt.windowArea, tt.id, 2); !slices.Equal(got, tt.want) {
+			t.Errorf("Node %d picked %v, expected %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestSasakiBlockSingleNode(t *testing.T) {
+	config := types.Config{Algorithm: types.Sasaki, NodeCount: 1, BlockSize: 4, InputType: types.Reverse}
+	report, err := runner(sasakiBlockSort(RunSasakiBlock[int]), simulator.IntValues)(testOptions(config))
+	checkSorted(t, report, err, 4)
+}
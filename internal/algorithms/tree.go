@@ -0,0 +1,201 @@
+package algorithms
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/simulator"
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+// TreePayload is one chunk of a sorted stream flowing up the tree; Done marks
+// the sender's last chunk.
+type TreePayload[V any] struct {
+	Values []V  `json:"values"`
+	Done   bool `json:"done,omitempty"`
+}
+
+// RunTreeMerge sorts on a binary tree by merging streams towards the root
+// (see mergeUp) and then hands every subtree its contiguous range of the
+// result, so that ranks follow an in-order walk of the tree (InOrder).
+func RunTreeMerge[V any](
+	n *types.Node[TreePayload[V]],
+	engine *simulator.SimulatorEngine[TreePayload[V]],
+	sendFunc func(net.Conn, types.Message[TreePayload[V]]) error,
+	compare func(a, b V) int,
+	debug bool,
+) {
+	k := len(n.Value.Values)
+	all, last, childLast := mergeUp(n, engine, sendFunc, compare, debug)
+
+	// A subtree's range is tagged with the round after the subtree root's
+	// last gather round, which both ends know, so the scatter never carries
+	// a round the receiver has already left behind.
+	if parent := n.Neighbor(types.Parent); parent != nil {
+		all = parent.Buffer.GetStepMessage(last + 1).Body.Values
+	}
+
+	leftSize := subtreeSize(2*n.ID+1, n.TotalNode) * k
+	ranges := [][]V{all[:leftSize], all[leftSize+k:]}
+	for i, values := range ranges {
+		if child := n.Neighbor(types.Child(i)); child != nil {
+			msg := types.Message[TreePayload[V]]{
+				SenderID:   n.ID,
+				ReceiverID: child.ID,
+				Round:      childLast[i] + 1,
+				Body:       TreePayload[V]{Values: values},
+				Type:       types.MsgData,
+			}
+			_ = sendFunc(child.Conn, msg)
+		}
+	}
+	n.Value.Values = all[leftSize : leftSize+k]
+	engine.IncrementClock(n)
+
+	if debug {
+		fmt.Printf("[Algo] Node %d: Tree Merge Complete. Range: [%v, %v]\n", n.ID, n.Value.Values[0], n.Value.Values[k-1])
+	}
+}
+
+// RunTreeGather is RunTreeMerge without the scatter: the root ends up
+// holding the whole sorted input and every other node holds nothing.
+func RunTreeGather[V any](
+	n *types.Node[TreePayload[V]],
+	engine *simulator.SimulatorEngine[TreePayload[V]],
+	sendFunc func(net.Conn, types.Message[TreePayload[V]]) error,
+	compare func(a, b V) int,
+	debug bool,
+) {
+	n.Value.Values, _, _ = mergeUp(n, engine, sendFunc, compare, debug)
+
+	if debug && n.ID == 0 {
+		fmt.Printf("[Algo] Node %d: Tree Gather Complete. %d values at the root\n", n.ID, len(n.Value.Values))
+	}
+}
+
+// mergeUp streams every subtree's values to the root in sorted order. Each
+// round a node receives the next chunk from each child still streaming,
+// merges its own sorted block with the children's buffered values and sends
+// up to k of them to its parent. A value may only be emitted while every
+// unfinished child has something buffered, as that child's next value could
+// be smaller. Leaves need no input, so the pipeline fills in the tree height
+// and a node with s values below it finishes after about s/k rounds. The
+// root collects its output and returns it; other nodes return nil. mergeUp
+// also returns the node's last round and the last round of each child
+// (indexed like Child(i)).
+func mergeUp[V any](
+	n *types.Node[TreePayload[V]],
+	engine *simulator.SimulatorEngine[TreePayload[V]],
+	sendFunc func(net.Conn, types.Message[TreePayload[V]]) error,
+	compare func(a, b V) int,
+	debug bool,
+) (collected []V, last int, childLast [2]int) {
+	parent := n.Neighbor(types.Parent)
+	var children []*types.Neighbor[TreePayload[V]]
+	for i := range 2 {
+		if child := n.Neighbor(types.Child(i)); child != nil {
+			children = append(children, child)
+		}
+	}
+
+	if debug {
+		fmt.Printf("[Algo] Node %d: Starting Tree Merge (%d children, %d values)\n", n.ID, len(children), len(n.Value.Values))
+	}
+
+	// Source 0 is the node's own block, source i+1 is child i.
+	queues := [][]V{n.Value.Values}
+	done := []bool{true}
+	for range children {
+		queues = append(queues, nil)
+		done = append(done, false)
+	}
+
+	chunk := max(len(n.Value.Values), 1)
+	for round := 1; ; round++ {
+		for i, child := range children {
+			if !done[i+1] {
+				msg := child.Buffer.GetStepMessage(round)
+				queues[i+1] = append(queues[i+1], msg.Body.Values...)
+				done[i+1] = msg.Body.Done
+				childLast[i] = round
+			}
+		}
+
+		var out []V
+		for parent == nil || len(out) < chunk {
+			source := nextSource(queues, done, compare)
+			if source < 0 {
+				break
+			}
+			out = append(out, queues[source][0])
+			queues[source] = queues[source][1:]
+		}
+
+		finished := true
+		for i := range queues {
+			finished = finished && done[i] && len(queues[i]) == 0
+		}
+
+		if parent != nil {
+			msg := types.Message[TreePayload[V]]{
+				SenderID:   n.ID,
+				ReceiverID: parent.ID,
+				Round:      round,
+				Body:       TreePayload[V]{Values: out, Done: finished},
+				Type:       types.MsgData,
+			}
+			_ = sendFunc(parent.Conn, msg)
+		} else {
+			collected = append(collected, out...)
+		}
+		engine.IncrementClock(n)
+
+		if finished {
+			return collected, round, childLast
+		}
+	}
+}
+
+// nextSource picks the queue holding the smallest head, preferring lower
+// sources on ties, or returns -1 if nothing can be emitted safely.
+func nextSource[V any](queues [][]V, done []bool, compare func(a, b V) int) int {
+	best := -1
+	for i, queue := range queues {
+		if len(queue) == 0 {
+			if !done[i] {
+				return -1
+			}
+			continue
+		}
+		if best < 0 || compare(queue[0], queues[best][0]) < 0 {
+			best = i
+		}
+	}
+	return best
+}
+
+// subtreeSize counts the nodes in the subtree rooted at id of a binary tree
+// of n nodes in heap layout.
+func subtreeSize(id, n int) int {
+	size := 0
+	for lo, hi := id, id; lo < n; lo, hi = 2*lo+1, 2*hi+2 {
+		size += min(hi, n-1) - lo + 1
+	}
+	return size
+}
+
+// InOrder lists the node IDs of a binary tree of n nodes in heap layout in
+// in-order, the order in which RunTreeMerge assigns ranks.
+func InOrder(n int) []int {
+	var order []int
+	var walk func(id int)
+	walk = func(id int) {
+		if id < n {
+			walk(2*id + 1)
+			order = append(order, id)
+			walk(2*id + 2)
+		}
+	}
+	walk(0)
+	return order
+}
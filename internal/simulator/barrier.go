@@ -10,6 +10,9 @@ type RoundBuffer[T any] struct {
 	mu         sync.Mutex
 	futureMsgs map[int]types.Message[T]
 	inbox      chan types.Message[T]
+
+	// flush, when set, runs before GetStepMessage blocks on an empty inbox.
+	flush func()
 }
 
 func NewRoundBuffer[T any](inbox chan types.Message[T]) *RoundBuffer[T] {
@@ -30,7 +33,15 @@ func (rb *RoundBuffer[T]) GetStepMessage(targetRound int) types.Message[T] {
 		}
 		rb.mu.Unlock()
 
-		msg := <-rb.inbox
+		var msg types.Message[T]
+		select {
+		case msg = <-rb.inbox:
+		default:
+			if rb.flush != nil {
+				rb.flush()
+			}
+			msg = <-rb.inbox
+		}
 
 		if msg.Round == targetRound {
 			return msg
@@ -42,23 +53,25 @@ func (rb *RoundBuffer[T]) GetStepMessage(targetRound int) types.Message[T] {
 	}
 }
 
-func WaitForNeighbors[T any](n *types.Node[T], currentRound int, leftBuf, rightBuf *RoundBuffer[T]) (leftMsg, rightMsg *types.Message[T]) {
+// WaitForNeighbors collects the current round's messages from both line
+// neighbors the node has.
+func WaitForNeighbors[T any](n *types.Node[T], currentRound int) (leftMsg, rightMsg *types.Message[T]) {
 	var wg sync.WaitGroup
 
-	if n.Position != types.Head {
+	if left := n.Neighbor(types.Left); left != nil {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			msg := leftBuf.GetStepMessage(currentRound)
+			msg := left.Buffer.GetStepMessage(currentRound)
 			leftMsg = &msg
 		}()
 	}
 
-	if n.Position != types.Tail {
+	if right := n.Neighbor(types.Right); right != nil {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			msg := rightBuf.GetStepMessage(currentRound)
+			msg := right.Buffer.GetStepMessage(currentRound)
 			rightMsg = &msg
 		}()
 	}
@@ -0,0 +1,296 @@
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/transport"
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+// dashboardInterval is how often the dashboard sends an update.
+const dashboardInterval = 500 * time.Millisecond
+
+// Dashboard serves a page over HTTP that follows a run live: the round
+// every node is in, the message rate and how far the array is from sorted,
+// pushed to the browser as server-sent events. Every node reports its own
+// values and round at the end of each round, so nothing is read from a
+// running node.
+type Dashboard[V, T any] struct {
+	name    string
+	values  func(payload T) []V
+	compare func(a, b V) int
+	order   []int
+	stats   *transport.Stats
+	start   time.Time
+
+	mu       sync.Mutex
+	nodes    []dashboardNode[V]
+	event    []byte
+	updated  chan struct{}
+	done     bool
+	messages uint64
+	lastTick time.Time
+
+	server *http.Server
+	addr   net.Addr
+	stop   chan struct{}
+}
+
+type dashboardNode[V any] struct {
+	started  bool
+	finished bool
+	rounds   int
+	values   []V
+}
+
+// dashboardEvent is the JSON of one update. Rounds holds the rounds every
+// node has completed, in ID order; Inversions is -1 until every node has
+// reported its values.
+type dashboardEvent struct {
+	Algorithm      string  `json:"algorithm"`
+	Nodes          int     `json:"nodes"`
+	Elapsed        float64 `json:"elapsed"`
+	Started        int     `json:"started"`
+	Finished       int     `json:"finished"`
+	Rounds         []int   `json:"rounds"`
+	Messages       uint64  `json:"messages"`
+	MessagesPerSec float64 `json:"messagesPerSec"`
+	Inversions     int     `json:"inversions"`
+	MaxInversions  int     `json:"maxInversions"`
+	Done           bool    `json:"done"`
+}
+
+// NewDashboard starts serving the dashboard of a run on addr. order lists
+// the node IDs in the order their values form the array.
+func NewDashboard[V, T any](addr, name string, order []int, algo Algorithm[V, T], compare func(a, b V) int, stats *transport.Stats) (*Dashboard[V, T], error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("dashboard: %w", err)
+	}
+
+	d := &Dashboard[V, T]{
+		name:     name,
+		values:   algo.Values,
+		compare:  compare,
+		order:    order,
+		stats:    stats,
+		start:    time.Now(),
+		nodes:    make([]dashboardNode[V], len(order)),
+		updated:  make(chan struct{}),
+		lastTick: time.Now(),
+		stop:     make(chan struct{}),
+	}
+	d.event = d.snapshot()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", d.serveIndex)
+	mux.HandleFunc("/events", d.serveEvents)
+	d.server, d.addr = &http.Server{Handler: mux}, listener.Addr()
+	go d.server.Serve(listener)
+	go d.tick()
+	return d, nil
+}
+
+// URL is the address the dashboard is served on.
+func (d *Dashboard[V, T]) URL() string {
+	return "http://" + d.addr.String()
+}
+
+// begin records n's initial values.
+func (d *Dashboard[V, T]) begin(n *types.Node[T]) {
+	values := slices.Clone(d.values(n.Value))
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.nodes[n.ID].started, d.nodes[n.ID].values = true, values
+}
+
+// endRound records n's values after its current round.
+func (d *Dashboard[V, T]) endRound(n *types.Node[T]) {
+	values := slices.Clone(d.values(n.Value))
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.nodes[n.ID].rounds, d.nodes[n.ID].values = n.Round+1, values
+}
+
+// finish records n's final values.
+func (d *Dashboard[V, T]) finish(n *types.Node[T]) {
+	values := slices.Clone(d.values(n.Value))
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.nodes[n.ID].finished, d.nodes[n.ID].values = true, values
+}
+
+func (d *Dashboard[V, T]) tick() {
+	ticker := time.NewTicker(dashboardInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			d.publish(false)
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// publish builds the next update and wakes every event stream.
+func (d *Dashboard[V, T]) publish(done bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.done = d.done || done
+	d.event = d.snapshot()
+	close(d.updated)
+	d.updated = make(chan struct{})
+}
+
+func (d *Dashboard[V, T]) snapshot() []byte {
+	now := time.Now()
+	event := dashboardEvent{
+		Algorithm:  d.name,
+		Nodes:      len(d.nodes),
+		Elapsed:    now.Sub(d.start).Seconds(),
+		Rounds:     make([]int, len(d.nodes)),
+		Inversions: -1,
+		Done:       d.done,
+	}
+	for id, node := range d.nodes {
+		event.Rounds[id] = node.rounds
+		if node.started {
+			event.Started++
+		}
+		if node.finished {
+			event.Finished++
+		}
+	}
+
+	event.Messages = d.stats.Snapshot().Messages
+	if elapsed := now.Sub(d.lastTick).Seconds(); elapsed > 0 {
+		event.MessagesPerSec = float64(event.Messages-d.messages) / elapsed
+	}
+	d.messages, d.lastTick = event.Messages, now
+
+	if event.Started == len(d.nodes) {
+		var array []V
+		for _, id := range d.order {
+			array = append(array, d.nodes[id].values...)
+		}
+		event.Inversions = CountInversions(array, d.compare)
+		event.MaxInversions = len(array) * (len(array) - 1) / 2
+	}
+	return mustMarshal(event)
+}
+
+// Close sends the final update, ends every event stream and stops the
+// server.
+func (d *Dashboard[V, T]) Close() error {
+	close(d.stop)
+	d.publish(true)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return d.server.Shutdown(ctx)
+}
+
+func (d *Dashboard[V, T]) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	for {
+		d.mu.Lock()
+		event, updated, done := d.event, d.updated, d.done
+		d.mu.Unlock()
+
+		fmt.Fprintf(w, "data: %s\n\n", event)
+		flusher.Flush()
+		if done {
+			return
+		}
+		select {
+		case <-updated:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (d *Dashboard[V, T]) serveIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, dashboardPage)
+}
+
+// dashboardPage draws one bar per node, as high as the rounds it has
+// completed, and the sortedness as 1 - inversions / max inversions.
+const dashboardPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Distributed Sorting Simulator</title>
+<style>
+body { font-family: monospace; margin: 2em; background: #fafafa; color: #222; }
+.stats { display: flex; gap: 2em; flex-wrap: wrap; margin: 1em 0; }
+.stat b { display: block; font-size: 1.6em; }
+.bar { width: 100%; height: 1.2em; background: #ddd; }
+.bar div { height: 100%; background: seagreen; width: 0; }
+canvas { width: 100%; height: 240px; background: white; border: 1px solid #ccc; }
+</style>
+</head>
+<body>
+<h2 id="title">Connecting...</h2>
+<div class="stats">
+  <div class="stat">Elapsed<b id="elapsed">-</b></div>
+  <div class="stat">Nodes started / finished<b id="nodes">-</b></div>
+  <div class="stat">Round (min / max)<b id="round">-</b></div>
+  <div class="stat">Messages/sec<b id="rate">-</b></div>
+  <div class="stat">Messages<b id="messages">-</b></div>
+  <div class="stat">Inversions<b id="inversions">-</b></div>
+</div>
+<p>Sortedness</p>
+<div class="bar"><div id="sorted"></div></div>
+<p>Rounds completed per node</p>
+<canvas id="rounds" width="1200" height="240"></canvas>
+<script>
+const $ = id => document.getElementById(id);
+const source = new EventSource("/events");
+source.onmessage = e => {
+  const s = JSON.parse(e.data);
+  const min = Math.min(...s.rounds), max = Math.max(...s.rounds);
+  $("title").textContent = s.algorithm + (s.done ? " (done)" : " (running)");
+  $("elapsed").textContent = s.elapsed.toFixed(1) + "s";
+  $("nodes").textContent = s.started + " / " + s.finished + " of " + s.nodes;
+  $("round").textContent = min + " / " + max;
+  $("rate").textContent = Math.round(s.messagesPerSec);
+  $("messages").textContent = s.messages;
+  if (s.inversions >= 0) {
+    $("inversions").textContent = s.inversions;
+    const sorted = s.maxInversions > 0 ? 1 - s.inversions / s.maxInversions : 1;
+    $("sorted").style.width = (100 * sorted).toFixed(2) + "%";
+  }
+
+  const canvas = $("rounds"), ctx = canvas.getContext("2d");
+  ctx.clearRect(0, 0, canvas.width, canvas.height);
+  ctx.fillStyle = "steelblue";
+  const w = canvas.width / s.nodes;
+  s.rounds.forEach((r, id) => {
+    const h = max > 0 ? r / max * canvas.height : 0;
+    ctx.fillRect(id * w, canvas.height - h, Math.max(w - (w > 3 ? 1 : 0), 0.5), h);
+  });
+  if (s.done) source.close();
+};
+</script>
+</body>
+</html>
+`
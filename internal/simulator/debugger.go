@@ -0,0 +1,460 @@
+package simulator
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+// Debugger is a global round barrier: every node stops at the end of each
+// round (and once before its first) until the debugger lets it go. When no
+// node can make progress any more, because each is parked, finished, or
+// waiting for a message nobody has sent yet, the run is paused and commands
+// are read from the input. A node that waits for a later message of a
+// parked node does not hold the barrier up, so algorithms whose nodes are
+// not in lockstep can be stepped too.
+type Debugger[T any] struct {
+	name  string
+	label func(payload T) string
+	state func(n *types.Node[T]) string
+	in    *bufio.Scanner
+
+	mu         sync.Mutex
+	cond       *sync.Cond
+	nodes      []debugNode[T]
+	generation int
+	pending    map[messageKey][]json.RawMessage
+
+	mode        debugMode
+	target      int
+	breakpoints map[int]bool
+	seen        []string
+}
+
+type debugNode[T any] struct {
+	node     *types.Node[T]
+	parked   bool
+	finished bool
+	round    int
+	waits    []messageKey
+}
+
+type debugMode int
+
+const (
+	debugStep debugMode = iota
+	debugContinue
+	debugDetached
+)
+
+// NewDebugger starts the debugger for nodeCount nodes, reading commands
+// from in. label formats a node's payload, state optionally describes the
+// rest of its algorithm state.
+func NewDebugger[T any](name string, nodeCount int, in io.Reader, label func(payload T) string, state func(n *types.Node[T]) string) *Debugger[T] {
+	d := &Debugger[T]{
+		name:        name,
+		label:       label,
+		state:       state,
+		in:          bufio.NewScanner(in),
+		nodes:       make([]debugNode[T], nodeCount),
+		pending:     make(map[messageKey][]json.RawMessage),
+		breakpoints: make(map[int]bool),
+		seen:        make([]string, nodeCount),
+	}
+	d.cond = sync.NewCond(&d.mu)
+	go d.run()
+	return d
+}
+
+// hook routes a neighbor's link through the debugger.
+func (d *Debugger[T]) hook(n *types.Node[T], nb *types.Neighbor[T]) {
+	nb.Conn = &debugConn[T]{Conn: nb.Conn, debugger: d, node: n.ID}
+	nb.Buffer = &debugReceiver[T]{StepReceiver: nb.Buffer, debugger: d, node: n.ID, peer: nb.ID}
+}
+
+// begin holds n before its first round; from then on the debugger follows
+// its links. Call it right before the algorithm runs.
+func (d *Debugger[T]) begin(n *types.Node[T]) {
+	d.mu.Lock()
+	d.nodes[n.ID].node = n
+	d.seen[n.ID] = d.label(n.Value)
+	d.mu.Unlock()
+	d.park(n.ID, n.Round-1)
+}
+
+// endRound holds n at the end of its current round.
+func (d *Debugger[T]) endRound(n *types.Node[T]) {
+	d.park(n.ID, n.Round)
+}
+
+// finish takes node id out of the barrier for good.
+func (d *Debugger[T]) finish(id int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.nodes[id].finished = true
+	d.cond.Broadcast()
+}
+
+func (d *Debugger[T]) park(id, round int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	node := &d.nodes[id]
+	node.parked, node.round = true, round
+	d.cond.Broadcast()
+	for generation := d.generation; d.generation == generation; {
+		d.cond.Wait()
+	}
+}
+
+// sent, beginWait and endWait skip nodes that have not begun, whose
+// messages belong to discovery.
+func (d *Debugger[T]) sent(key messageKey, body json.RawMessage) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.nodes[key.sender].node == nil {
+		return
+	}
+	d.pending[key] = append(d.pending[key], body)
+	d.cond.Broadcast()
+}
+
+func (d *Debugger[T]) beginWait(id int, key messageKey) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.nodes[id].node == nil {
+		return
+	}
+	d.nodes[id].waits = append(d.nodes[id].waits, key)
+	d.cond.Broadcast()
+}
+
+func (d *Debugger[T]) endWait(id int, key messageKey) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	node := &d.nodes[id]
+	if node.node == nil {
+		return
+	}
+	i := slices.Index(node.waits, key)
+	node.waits = slices.Delete(node.waits, i, i+1)
+	if bodies := d.pending[key]; len(bodies) > 1 {
+		d.pending[key] = bodies[1:]
+	} else {
+		delete(d.pending, key)
+	}
+	d.cond.Broadcast()
+}
+
+// settled reports whether no node can move on before the debugger releases
+// the parked ones: a running node only counts as stuck if every message it
+// waits for is yet to be sent.
+func (d *Debugger[T]) settled() bool {
+	for _, node := range d.nodes {
+		if node.parked || node.finished {
+			continue
+		}
+		if len(node.waits) == 0 {
+			return false
+		}
+		for _, key := range node.waits {
+			if len(d.pending[key]) > 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// run releases the parked nodes every time the run settles, after reading
+// commands whenever it should pause there.
+func (d *Debugger[T]) run() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for {
+		for !d.settled() {
+			d.cond.Wait()
+		}
+		parked, finished := 0, 0
+		for _, node := range d.nodes {
+			if node.parked {
+				parked++
+			} else if node.finished {
+				finished++
+			}
+		}
+		if finished == len(d.nodes) {
+			return
+		}
+		if parked == 0 {
+			fmt.Printf("[Debug] No node can make progress\n")
+			d.cond.Wait()
+			continue
+		}
+
+		if reason, pause := d.pauseReason(); pause {
+			d.printNodes(reason)
+			d.readCommands()
+		}
+		for id := range d.nodes {
+			d.nodes[id].parked = false
+		}
+		d.generation++
+		d.cond.Broadcast()
+	}
+}
+
+// pauseReason records every node's current values and decides whether to
+// stop here: always when stepping, at the target round when continuing,
+// and whenever a node with a breakpoint changed its values.
+func (d *Debugger[T]) pauseReason() (string, bool) {
+	var changed []string
+	for id, node := range d.nodes {
+		if node.node == nil || !node.parked {
+			continue
+		}
+		value := d.label(node.node.Value)
+		if d.breakpoints[id] && value != d.seen[id] {
+			changed = append(changed, fmt.Sprintf("Node %d changed %s -> %s", id, d.seen[id], value))
+		}
+		d.seen[id] = value
+	}
+
+	switch {
+	case d.mode == debugDetached:
+		return "", false
+	case changed != nil:
+		d.mode = debugStep
+		return "breakpoint: " + strings.Join(changed, ", "), true
+	case d.mode == debugStep:
+		return "", true
+	}
+	if lowest, _ := d.parkedRounds(); lowest >= d.target {
+		d.mode = debugStep
+		return fmt.Sprintf("reached round %d", d.target), true
+	}
+	return "", false
+}
+
+// parkedRounds returns the lowest and highest round the parked nodes have
+// completed.
+func (d *Debugger[T]) parkedRounds() (lowest, highest int) {
+	lowest, highest = math.MaxInt, math.MinInt
+	for _, node := range d.nodes {
+		if node.parked {
+			lowest, highest = min(lowest, node.round), max(highest, node.round)
+		}
+	}
+	return lowest, highest
+}
+
+func (d *Debugger[T]) printNodes(reason string) {
+	lowest, highest := d.parkedRounds()
+	switch {
+	case highest < 0:
+		fmt.Printf("\n--- %s: initial state", d.name)
+	case lowest == highest:
+		fmt.Printf("\n--- %s: after round %d", d.name, lowest)
+	default:
+		fmt.Printf("\n--- %s: after rounds %d to %d", d.name, max(lowest, 0), highest)
+	}
+	if reason != "" {
+		fmt.Printf(" (%s)", reason)
+	}
+	fmt.Printf(" ---\n")
+	for id := range d.nodes {
+		fmt.Printf("Node %d: %s\n", id, d.describe(id))
+	}
+}
+
+// describe sums up node id in one line.
+func (d *Debugger[T]) describe(id int) string {
+	node := d.nodes[id]
+	if node.node == nil {
+		return "starting"
+	}
+	line := d.label(node.node.Value)
+	if d.state != nil && node.parked && node.round >= 0 {
+		if state := d.state(node.node); state != "" {
+			line += " " + state
+		}
+	}
+	switch {
+	case node.finished:
+		line += fmt.Sprintf(", finished after round %d", node.node.Round-1)
+	case node.parked && node.round < 0:
+		line += ", about to start"
+	case node.parked:
+		line += fmt.Sprintf(", parked after round %d", node.round)
+	default:
+		var waits []string
+		for _, key := range node.waits {
+			waits = append(waits, fmt.Sprintf("round %d from Node %d", key.round, key.sender))
+		}
+		line += ", waiting for " + strings.Join(waits, " and ")
+	}
+	if d.breakpoints[id] {
+		line += " [break]"
+	}
+	return line
+}
+
+// inspect prints node id in full: its payload as sent on the wire and the
+// messages sent to it that it has not read yet.
+func (d *Debugger[T]) inspect(id int) {
+	node := d.nodes[id]
+	fmt.Printf("Node %d: %s\n", id, d.describe(id))
+	if node.node == nil {
+		return
+	}
+	fmt.Printf("  Round:   %d\n", node.node.Round)
+	fmt.Printf("  Payload: %s\n", mustMarshal(node.node.Value))
+
+	var keys []messageKey
+	for key := range d.pending {
+		if key.receiver == id {
+			keys = append(keys, key)
+		}
+	}
+	slices.SortFunc(keys, func(a, b messageKey) int {
+		if a.round != b.round {
+			return a.round - b.round
+		}
+		return a.sender - b.sender
+	})
+	if keys == nil {
+		fmt.Printf("  Buffered: none\n")
+		return
+	}
+	fmt.Printf("  Buffered:\n")
+	for _, key := range keys {
+		for _, body := range d.pending[key] {
+			fmt.Printf("    round %d from Node %d: %s\n", key.round, key.sender, body)
+		}
+	}
+}
+
+const debugHelp = `Commands:
+  step, s (or an empty line)  run one more round
+  continue, c [R]             run until every node is past round R (to the end without R)
+  inspect, i N                show node N's payload and buffered messages
+  nodes, n                    list every node again
+  break, b N                  pause whenever node N's values change
+  delete, d N                 remove the breakpoint on node N
+  quit, q                     stop pausing and run to the end
+  help, h                     show this help`
+
+// readCommands reads commands until one of them resumes the run. The lock
+// is released while waiting for input, so messages still in flight can be
+// delivered meanwhile.
+func (d *Debugger[T]) readCommands() {
+	for {
+		fmt.Printf("(debug) ")
+		d.mu.Unlock()
+		ok := d.in.Scan()
+		d.mu.Lock()
+		if !ok {
+			fmt.Println()
+			d.mode = debugDetached
+			return
+		}
+
+		fields := strings.Fields(d.in.Text())
+		if len(fields) == 0 {
+			d.mode = debugStep
+			return
+		}
+		arg := -1
+		if len(fields) > 1 {
+			n, err := strconv.Atoi(fields[1])
+			if err != nil {
+				fmt.Printf("Invalid number %q\n", fields[1])
+				continue
+			}
+			arg = n
+		}
+		node := func() bool {
+			if arg < 0 || arg >= len(d.nodes) {
+				fmt.Printf("Expected a node between 0 and %d\n", len(d.nodes)-1)
+				return false
+			}
+			return true
+		}
+
+		switch fields[0] {
+		case "step", "s":
+			d.mode = debugStep
+			return
+		case "continue", "c":
+			d.mode, d.target = debugContinue, arg
+			if arg < 0 {
+				d.target = math.MaxInt
+			}
+			return
+		case "inspect", "i":
+			if node() {
+				d.inspect(arg)
+			}
+		case "nodes", "n":
+			d.printNodes("")
+		case "break", "b":
+			if node() {
+				d.breakpoints[arg] = true
+				fmt.Printf("Breakpoint on Node %d\n", arg)
+			}
+		case "delete", "d":
+			if node() {
+				delete(d.breakpoints, arg)
+				fmt.Printf("Removed breakpoint on Node %d\n", arg)
+			}
+		case "quit", "q":
+			d.mode = debugDetached
+			return
+		case "help", "h":
+			fmt.Println(debugHelp)
+		default:
+			fmt.Printf("Unknown command %q, type help for a list\n", fields[0])
+		}
+	}
+}
+
+type debugConn[T any] struct {
+	net.Conn
+	debugger *Debugger[T]
+	node     int
+}
+
+func (c *debugConn[T]) Write(p []byte) (int, error) {
+	var head messageHead
+	if json.Unmarshal(p, &head) == nil && head.Type == types.MsgData {
+		c.debugger.sent(messageKey{sender: c.node, receiver: head.ReceiverID, round: head.Round}, head.Body)
+	}
+	return c.Conn.Write(p)
+}
+
+func (c *debugConn[T]) Unwrap() net.Conn {
+	return c.Conn
+}
+
+type debugReceiver[T any] struct {
+	types.StepReceiver[T]
+	debugger *Debugger[T]
+	node     int
+	peer     int
+}
+
+func (r *debugReceiver[T]) GetStepMessage(round int) types.Message[T] {
+	key := messageKey{sender: r.peer, receiver: r.node, round: round}
+	r.debugger.beginWait(r.node, key)
+	msg := r.StepReceiver.GetStepMessage(round)
+	r.debugger.endWait(r.node, key)
+	return msg
+}
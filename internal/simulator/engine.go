@@ -3,11 +3,12 @@ package simulator
 import (
 	"encoding/json"
 	"fmt"
+	"math/rand"
 	"net"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
-	"math/rand"
 
 	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/transport"
 	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
@@ -15,28 +16,115 @@ import (
 
 type SimulatorEngine[T any] struct {
 	TotalNodes  int
+	Rows, Cols  int
 	ActiveNodes int32
 	Done        chan bool
 	WaitGroup   sync.WaitGroup
+	Speed       SpeedProfile
+	Topology    types.Topology
+	Timeline    *Timeline
+	SpaceTime   *SpaceTime[T]
+	Debugger    *Debugger[T]
+	snapshots   roundObserver[T]
+	dashboard   roundObserver[T]
+
+	// Adaptive lets algorithms that support it stop as soon as every node
+	// is stable, agreed on through a TerminationDetector or, with
+	// SharedTermination, the in-process vote (SignalStable/ResetStability).
+	Adaptive    bool
+	Termination types.TerminationMode
+
+	votesMu  sync.Mutex
+	votes    map[int]*stabilityVote
+	doneOnce sync.Once
+}
+
+// stabilityVote collects the votes every node casts for one round.
+type stabilityVote struct {
+	cast, stable, read int
+	stableAll          bool
+	decided            chan struct{}
 }
 
 func NewEngine[T any](n int) *SimulatorEngine[T] {
 	return &SimulatorEngine[T]{
 		TotalNodes: n,
 		Done:       make(chan bool),
+		votes:      make(map[int]*stabilityVote),
 	}
 }
 
+// IncrementClock ends n's round. A batching node's messages stay held across
+// rounds until it waits for something, so a node that finds its input
+// already buffered, as in a streaming tree merge, writes several rounds of
+// output at once.
 func (e *SimulatorEngine[T]) IncrementClock(n *types.Node[T]) {
+	if e.Timeline != nil {
+		e.Timeline.endRound(n.ID, n.Round)
+	}
+	if e.SpaceTime != nil {
+		e.SpaceTime.endRound(n)
+	}
+	if e.snapshots != nil {
+		e.snapshots.endRound(n)
+	}
+	if e.dashboard != nil {
+		e.dashboard.endRound(n)
+	}
+	if e.Debugger != nil {
+		FlushLinks(n)
+		e.Debugger.endRound(n)
+	}
 	n.Round++
+	e.compute(n)
+}
+
+// compute spends the node's compute time for the round it is entering,
+// before the node sends anything in it, so a slow node's messages go out
+// late instead of the node only stalling after it has sent them. What the
+// node still holds back goes out first.
+func (e *SimulatorEngine[T]) compute(n *types.Node[T]) {
+	if e.Speed == nil {
+		return
+	}
+	if delay := e.Speed.Delay(n.ID, n.Round); delay > 0 {
+		FlushLinks(n)
+		time.Sleep(delay)
+	}
 }
 
-func (e *SimulatorEngine[T]) InitialSetup(n *types.Node[T], config types.Config, leftBuf, rightBuf *RoundBuffer[T], debug bool) error {
+func (e *SimulatorEngine[T]) InitialSetup(n *types.Node[T], config types.Config, debug bool) error {
 	if debug {
 		fmt.Printf("[Setup] Node %d: Starting Discovery Phase...\n", n.ID)
 	}
 
-	total, err := DiscoverTotalNodes(n, leftBuf, rightBuf)
+	e.Topology = config.Topology
+
+	var total int
+	var err error
+	switch config.Topology {
+	case types.Ring:
+		total, n.Rank, err = DiscoverRing(n)
+		if err == nil && debug {
+			fmt.Printf("[Setup] Node %d: Ring rank %d from leader\n", n.ID, n.Rank)
+		}
+	case types.Hypercube:
+		total = 1 << len(n.Neighbors)
+		n.TotalNode = total
+	case types.Complete:
+		total = len(n.Neighbors) + 1
+		n.TotalNode = total
+	case types.Mesh:
+		e.Rows, e.Cols, err = DiscoverMesh(n)
+		total = e.Rows * e.Cols
+		if err == nil && debug {
+			fmt.Printf("[Setup] Node %d: Mesh %dx%d\n", n.ID, e.Rows, e.Cols)
+		}
+	case types.Tree:
+		total, err = DiscoverTree(n)
+	default:
+		total, err = DiscoverTotalNodes(n)
+	}
 	if err != nil {
 		return err
 	}
@@ -48,175 +136,306 @@ func (e *SimulatorEngine[T]) InitialSetup(n *types.Node[T], config types.Config,
 	return nil
 }
 
-func SetupNode[T any](n *types.Node[T], debug bool) error {
+// NeighborHook wraps a neighbor's Conn and receivers, e.g. to record the
+// messages the node sends and reads.
+type NeighborHook[T any] func(n *types.Node[T], nb *types.Neighbor[T])
+
+// NodeLinks holds the listener and links SetupNode opened for a node, so
+// CloseLinks can shut them down once the run is over.
+type NodeLinks struct {
+	listener net.Listener
+	dialed   []*transport.Link
+	served   []*transport.Link
+	done     chan struct{}
+}
+
+// CloseLinks shuts down the links and listeners of every node. The dialed
+// ends go first: a served end never redials, while a dialed end whose peer
+// closed first would keep trying to reconnect.
+func CloseLinks(nodes []*NodeLinks) {
+	for _, nl := range nodes {
+		if nl == nil {
+			continue
+		}
+		close(nl.done)
+		for _, link := range nl.dialed {
+			link.Close()
+		}
+	}
+	for _, nl := range nodes {
+		if nl == nil {
+			continue
+		}
+		nl.listener.Close()
+		for _, link := range nl.served {
+			link.Close()
+		}
+	}
+}
 
-	mainInbox := make(chan types.Message[T], 500)
+// SetupNode connects n to the neighbors its topology gives it (see
+// neighborSpecs) and fills n.Neighbors. Every neighbor gets its own inbox
+// and RoundBuffer, and a message is delivered to the neighbor whose link it
+// arrived on. MsgTerm messages go to the neighbor's Control buffer instead.
+// A full inbox holds up the link rather than dropping the message, until
+// CloseLinks releases it.
+// The hooks run on every neighbor, in order, before anything reads from it,
+// so a neighbor's Conn and receivers never change once the node runs.
+func SetupNode[T any](n *types.Node[T], config types.Config, opts transport.Options, debug bool, hooks ...NeighborHook[T]) (*NodeLinks, error) {
+	specs := neighborSpecs(n, config)
+
+	// A node about to wait for a message first writes out what its links
+	// hold back, since the neighbor may be waiting for it in turn.
+	var nodeLinks []*transport.Link
+	flush := func() {
+		for _, link := range nodeLinks {
+			link.Flush()
+		}
+	}
 
-	go func() {
-		for msg := range mainInbox {
-			if msg.Type == types.MsgSync {
-				continue
-			}
-			if msg.SenderID < n.ID {
-				select {
-				case n.LeftInbox <- msg:
-				default:
-				}
-			} else if msg.SenderID > n.ID {
-				select {
-				case n.RightInbox <- msg:
-				default:
-				}
-			}
+	n.Neighbors = make(map[types.Direction]*types.Neighbor[T], len(specs))
+	for _, spec := range specs {
+		inbox := make(chan types.Message[T], 500)
+		controlInbox := make(chan types.Message[T], 500)
+		buffer, control := NewRoundBuffer(inbox), NewRoundBuffer(controlInbox)
+		if opts.Batch {
+			buffer.flush, control.flush = flush, flush
+		}
+		n.Neighbors[spec.label] = &types.Neighbor[T]{
+			ID:           spec.peerID,
+			Label:        spec.label,
+			Inbox:        inbox,
+			Buffer:       buffer,
+			ControlInbox: controlInbox,
+			Control:      control,
 		}
-	}()
+	}
 
-	addr := fmt.Sprintf(":%d", 8000+n.ID)
+	addr := fmt.Sprintf(":%d", opts.Port(n.ID))
 	listener, err := net.Listen("tcp", addr)
 	if err != nil {
-		return fmt.Errorf("listen error: %w", err)
+		return nil, fmt.Errorf("listen error: %w", err)
 	}
+	nl := &NodeLinks{listener: listener, done: make(chan struct{})}
 
-	go func() {
+	if debug {
+		opts.OnDisconnect = func(peerID int, err error) {
+			fmt.Printf("[Net] Node %d: Lost connection to %d (%v), reconnecting...\n", n.ID, peerID, err)
+		}
+		opts.OnReconnect = func(peerID int, downtime time.Duration, resent int) {
+			fmt.Printf("[Net] Node %d: Reconnected to %d after %v, resent %d messages\n", n.ID, peerID, downtime.Round(time.Millisecond), resent)
+		}
+	}
+
+	receive := func(nb *types.Neighbor[T], conn net.Conn) {
+		dec := json.NewDecoder(conn)
 		for {
-			conn, err := listener.Accept()
-			if err != nil {
+			var msg types.Message[T]
+			if err := dec.Decode(&msg); err != nil {
+				conn.Close()
+				return
+			}
+			if msg.Type == types.MsgSync {
 				continue
 			}
+			if !msg.Timestamp.IsZero() {
+				opts.Stats.ObserveLatency(time.Since(msg.Timestamp))
+			}
+			transport.Observe(conn, msg)
+			msg.IncomingDirection = nb.Label
+			inbox := nb.Inbox
+			if msg.Type == types.MsgTerm {
+				inbox = nb.ControlInbox
+			}
+			select {
+			case inbox <- msg:
+			case <-nl.done:
+				return
+			}
+		}
+	}
+
+	connect := func(spec neighborSpec, link *transport.Link) {
+		nodeLinks = append(nodeLinks, link)
+		nb := n.Neighbors[spec.label]
+		nb.Conn = link
+		for _, hook := range hooks {
+			hook(n, nb)
+		}
+		go receive(nb, link)
+	}
+
+	links := make(map[int]*transport.Link)
+	for _, spec := range specs {
+		if !spec.dial {
+			link := transport.NewLink(n.ID, spec.peerID, opts)
+			links[spec.peerID] = link
+			nl.served = append(nl.served, link)
+			connect(spec, link)
+		}
+	}
+	go transport.ServeLinks(listener, links)
 
-			go func(c net.Conn) {
-				dec := json.NewDecoder(c)
-				var handshake types.Message[T]
-
-				if err := dec.Decode(&handshake); err != nil {
-					c.Close()
-					return
-				}
-
-				switch handshake.SenderID {
-				case n.ID - 1:
-					n.LeftConn = c
-					if debug {
-						fmt.Printf("[Net] Node %d: Accepted LeftConn from %d\n", n.ID, handshake.SenderID)
-					}
-				case n.ID + 1:
-					n.RightConn = c
-					if debug {
-						fmt.Printf("[Net] Node %d: Accepted RightConn from %d\n", n.ID, handshake.SenderID)
-					}
-				}
-
-				for {
-					var msg types.Message[T]
-					if err := dec.Decode(&msg); err != nil {
-						c.Close()
-						return
-					}
-					mainInbox <- msg
-				}
-			}(conn)
-		}
-	}()
-
-	if n.Position != types.Tail {
-		targetID := n.ID + 1
-		conn, err := transport.DialNeighbor(targetID)
-		if err != nil {
-			return err
-		}
-		n.RightConn = conn
-		if debug {
-			fmt.Printf("[Net] Node %d: Connected to Right Neighbor %d\n", n.ID, targetID)
-		}
-
-		handshake := types.Message[T]{Type: types.MsgSync, SenderID: n.ID}
-		json.NewEncoder(conn).Encode(handshake)
-
-		go func(c net.Conn) {
-			dec := json.NewDecoder(c)
-			for {
-				var msg types.Message[T]
-				if err := dec.Decode(&msg); err != nil {
-					c.Close()
-					return
-				}
-				mainInbox <- msg
+	for _, spec := range specs {
+		if spec.dial {
+			link, err := transport.DialLink(n.ID, spec.peerID, opts)
+			if err != nil {
+				CloseLinks([]*NodeLinks{nl})
+				return nil, err
+			}
+			nl.dialed = append(nl.dialed, link)
+			connect(spec, link)
+			if debug {
+				fmt.Printf("[Net] Node %d: Connected to %s Neighbor %d\n", n.ID, spec.label, spec.peerID)
 			}
-		}(conn)
+		}
 	}
 
-	return nil
+	return nl, nil
 }
 
-func (e *SimulatorEngine[T]) SignalStable() {
-	if atomic.AddInt32(&e.ActiveNodes, -1) == 0 {
-		e.Done <- true
+// FlushLinks writes out the messages n's links hold back for batching.
+func FlushLinks[T any](n *types.Node[T]) {
+	for _, nb := range n.Neighbors {
+		transport.Flush(nb.Conn)
 	}
 }
 
-func (e *SimulatorEngine[T]) ResetStability() {
-	atomic.StoreInt32(&e.ActiveNodes, int32(e.TotalNodes))
+// SignalStable and ResetStability cast a node's vote for a round: stable if
+// nothing the node holds can change any more, as far as it can tell, and
+// active otherwise. Every node votes exactly once per round.
+func (e *SimulatorEngine[T]) SignalStable(round int) {
+	e.castVote(round, true)
 }
 
-func (e *SimulatorEngine[T]) CheckTermination() bool {
-	select {
-	case <-e.Done:
-		return true
-	default:
-		return false
+func (e *SimulatorEngine[T]) ResetStability(round int) {
+	e.castVote(round, false)
+}
+
+// CheckTermination waits until every node has voted for round and reports
+// whether all of them were stable. All nodes get the same answer, so they
+// stop after the same round. Once a round is stable Done is closed, and
+// ActiveNodes holds the number of active votes of the last decided round.
+func (e *SimulatorEngine[T]) CheckTermination(round int) bool {
+	v := e.vote(round)
+	<-v.decided
+
+	e.votesMu.Lock()
+	defer e.votesMu.Unlock()
+	if v.read++; v.read == e.TotalNodes {
+		delete(e.votes, round)
 	}
+	return v.stableAll
 }
 
-func DiscoverTotalNodes[T any](n *types.Node[T], leftBuf, rightBuf *RoundBuffer[T]) (int, error) {
-	leftDist, rightDist := -1, -1
+func (e *SimulatorEngine[T]) vote(round int) *stabilityVote {
+	e.votesMu.Lock()
+	defer e.votesMu.Unlock()
+	v, ok := e.votes[round]
+	if !ok {
+		v = &stabilityVote{decided: make(chan struct{})}
+		e.votes[round] = v
+	}
+	return v
+}
 
+func (e *SimulatorEngine[T]) castVote(round int, stable bool) {
+	v := e.vote(round)
+
+	e.votesMu.Lock()
+	defer e.votesMu.Unlock()
+	v.cast++
+	if stable {
+		v.stable++
+	}
+	if v.cast < e.TotalNodes {
+		return
+	}
+
+	v.stableAll = v.stable == e.TotalNodes
+	atomic.StoreInt32(&e.ActiveNodes, int32(e.TotalNodes-v.stable))
+	if v.stableAll {
+		e.doneOnce.Do(func() { close(e.Done) })
+	}
+	close(v.decided)
+}
+
+func DiscoverTotalNodes[T any](n *types.Node[T]) (int, error) {
 	time.Sleep(200 * time.Millisecond)
 
-	sendSeed := func(conn net.Conn, dist uint64) error {
-		msg := types.Message[T]{Type: types.MsgInit, SenderID: n.ID, Round: 0, Sequence: dist}
-		return transport.SendMessage(conn, msg)
+	leftDist, rightDist, err := discoverAxis(n.ID, n.Neighbor(types.Left), n.Neighbor(types.Right))
+	if err != nil {
+		return -1, err
 	}
 
-	if n.Position == types.Head {
-		leftDist = 0
-		if n.RightConn == nil {
-			return -1, fmt.Errorf("head rightconn nil")
-		}
-		if err := sendSeed(n.RightConn, uint64(leftDist)); err != nil {
-			return -1, err
-		}
+	n.TotalNode = leftDist + rightDist + 1
+	return n.TotalNode, nil
+}
+
+// DiscoverMesh runs the line discovery along the node's row and column and
+// returns the mesh dimensions.
+func DiscoverMesh[T any](n *types.Node[T]) (rows, cols int, err error) {
+	time.Sleep(200 * time.Millisecond)
+
+	leftDist, rightDist, err := discoverAxis(n.ID, n.Neighbor(types.Left), n.Neighbor(types.Right))
+	if err != nil {
+		return -1, -1, err
+	}
+	upDist, downDist, err := discoverAxis(n.ID, n.Neighbor(types.Up), n.Neighbor(types.Down))
+	if err != nil {
+		return -1, -1, err
 	}
-	if n.Position == types.Tail {
-		rightDist = 0
-		if n.LeftConn == nil {
-			return -1, fmt.Errorf("tail leftconn nil")
+
+	rows, cols = upDist+downDist+1, leftDist+rightDist+1
+	n.TotalNode = rows * cols
+	return rows, cols, nil
+}
+
+// discoverAxis measures the distance to both ends of a line of nodes: each
+// end sends a zero seed inwards and every node forwards it incremented. A
+// missing neighbor marks the end of the line on that side.
+func discoverAxis[T any](id int, prev, next *types.Neighbor[T]) (prevDist, nextDist int, err error) {
+	prevDist, nextDist = -1, -1
+
+	sendSeed := func(nb *types.Neighbor[T], dist uint64) error {
+		msg := types.Message[T]{Type: types.MsgInit, SenderID: id, ReceiverID: nb.ID, Round: 0, Sequence: dist}
+		return transport.SendMessage(nb.Conn, msg)
+	}
+
+	if prev == nil {
+		prevDist = 0
+		if next != nil {
+			if err := sendSeed(next, uint64(prevDist)); err != nil {
+				return -1, -1, err
+			}
 		}
-		if err := sendSeed(n.LeftConn, uint64(rightDist)); err != nil {
-			return -1, err
+	}
+	if next == nil {
+		nextDist = 0
+		if prev != nil {
+			if err := sendSeed(prev, uint64(nextDist)); err != nil {
+				return -1, -1, err
+			}
 		}
 	}
 
-	for leftDist == -1 || rightDist == -1 {
-		if leftDist == -1 {
-			msg := leftBuf.GetStepMessage(0)
-			leftDist = int(msg.Sequence) + 1
-			if n.Position != types.Tail {
-				msg.Sequence = uint64(leftDist)
-				transport.SendMessage(n.RightConn, msg)
+	for prevDist == -1 || nextDist == -1 {
+		if prevDist == -1 {
+			msg := prev.Buffer.GetStepMessage(0)
+			prevDist = int(msg.Sequence) + 1
+			if next != nil {
+				sendSeed(next, uint64(prevDist))
 			}
 		}
-		if rightDist == -1 {
-			msg := rightBuf.GetStepMessage(0)
-			rightDist = int(msg.Sequence) + 1
-			if n.Position != types.Head {
-				msg.Sequence = uint64(rightDist)
-				transport.SendMessage(n.LeftConn, msg)
+		if nextDist == -1 {
+			msg := next.Buffer.GetStepMessage(0)
+			nextDist = int(msg.Sequence) + 1
+			if prev != nil {
+				sendSeed(prev, uint64(nextDist))
 			}
 		}
 	}
-
-	n.TotalNode = leftDist + rightDist + 1
-	return n.TotalNode, nil
+	return prevDist, nextDist, nil
 }
 
 func GenerateInitialValue(id int, config types.Config) int {
@@ -228,8 +447,38 @@ func GenerateInitialValue(id int, config types.Config) int {
 		val = int(config.NodeCount) - 1 - id
 	case types.Random:
 		r := rand.New(rand.NewSource(time.Now().UnixNano() + int64(id)))
-		val = r.Intn(1000)
+		val = r.Intn(randomRange(config, 1))
+	case types.NearlySorted:
+		r := rand.New(rand.NewSource(time.Now().UnixNano() + int64(id)))
+		val = id + r.Intn(types.NearlySortedWindow)
 	}
 	return val
 }
 
+// GenerateInitialBlock returns the BlockSize values node id starts with,
+// sorted. Sorted and reverse inputs cover the whole N*BlockSize range.
+func GenerateInitialBlock(id int, config types.Config) []int {
+	k := max(int(config.BlockSize), 1)
+	if k == 1 {
+		return []int{GenerateInitialValue(id, config)}
+	}
+
+	total := int(config.NodeCount) * k
+	r := rand.New(rand.NewSource(time.Now().UnixNano() + int64(id)))
+	block := make([]int, k)
+	for j := range block {
+		pos := id*k + j
+		switch config.InputType {
+		case types.Sorted:
+			block[j] = pos
+		case types.Reverse:
+			block[j] = total - 1 - pos
+		case types.Random:
+			block[j] = r.Intn(randomRange(config, k))
+		case types.NearlySorted:
+			block[j] = pos + r.Intn(types.NearlySortedWindow)
+		}
+	}
+	sort.Ints(block)
+	return block
+}
@@ -0,0 +1,53 @@
+package simulator
+
+import (
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/transport"
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+// flood sends more messages to the right than the neighbor's inbox holds,
+// and nobody reads them.
+func flood(n *types.Node[int], engine *SimulatorEngine[int], sendFunc func(net.Conn, types.Message[int]) error, compare func(a, b int) int, debug bool) {
+	if nb := n.Neighbors[types.Right]; nb != nil {
+		for i := range 600 {
+			sendFunc(nb.Conn, types.Message[int]{Round: i, SenderID: n.ID, Type: types.MsgData, Body: i})
+		}
+	}
+	FlushLinks(n)
+}
+
+func TestRunReleasesLinks(t *testing.T) {
+	before := runtime.NumGoroutine()
+	opts := RunOptions{
+		Config: types.Config{
+			Algorithm:   types.OddEven,
+			Topology:    types.Line,
+			NodeCount:   3,
+			BlockSize:   1,
+			Termination: types.MessageTermination,
+		},
+		// Away from the algorithm and transport tests' ports.
+		Transport: transport.Options{NoDelay: true, PortBase: 19000},
+	}
+	algo := Algorithm[int, int]{
+		Payload: func(values []int) int { return values[0] },
+		Values:  func(v int) []int { return []int{v} },
+		Run:     flood,
+	}
+	if _, err := Run(opts, algo, IntValues); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("%d goroutines left running after Run, expected %d", after, before)
+	}
+}
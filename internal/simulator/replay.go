@@ -0,0 +1,436 @@
+package simulator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/transport"
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+// ReplayOptions selects a trace to replay instead of running over the
+// network. Node is the one node to replay, or -1 for all of them.
+type ReplayOptions struct {
+	Path string
+	Node int
+}
+
+// NodeReplay is the outcome of replaying one node: how many recorded sends
+// and receives it went through, and where it first left the recording.
+type NodeReplay struct {
+	Node       int
+	Sends      int
+	Receives   int
+	Divergence error
+}
+
+// ReadTraceHeader reads the header of a trace written by TraceRecorder.
+func ReadTraceHeader(path string) (TraceHeader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return TraceHeader{}, err
+	}
+	defer f.Close()
+
+	var header TraceHeader
+	if err := json.NewDecoder(f).Decode(&header); err != nil {
+		return TraceHeader{}, fmt.Errorf("%s: reading trace header: %w", path, err)
+	}
+	return header, nil
+}
+
+// LoadTrace reads a trace and groups its events by node, in Seq order.
+func LoadTrace(path string) (TraceHeader, map[int][]TraceEvent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return TraceHeader{}, nil, err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	var header TraceHeader
+	if err := dec.Decode(&header); err != nil {
+		return TraceHeader{}, nil, fmt.Errorf("%s: reading trace header: %w", path, err)
+	}
+	events := make(map[int][]TraceEvent)
+	for {
+		var event TraceEvent
+		if err := dec.Decode(&event); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return TraceHeader{}, nil, fmt.Errorf("%s: %w", path, err)
+		}
+		events[event.Node] = append(events[event.Node], event)
+	}
+	return header, events, nil
+}
+
+// replayTrace re-executes the nodes of a recorded run without a network.
+// Each node starts from its recorded initial payload, every message it
+// reads comes from its recorded receives, and every message it sends is
+// checked against its recorded sends, so a node behaves exactly as in the
+// recording until its code does something different. Nodes never talk to
+// each other, which makes the replay deterministic and lets a single node
+// be replayed on its own.
+func replayTrace[V, T any](opts RunOptions, algo Algorithm[V, T], kind ValueKind[V]) (Report[V], error) {
+	header, events, err := LoadTrace(opts.Replay.Path)
+	if err != nil {
+		return Report[V]{}, err
+	}
+	nodeCount := int(header.Config.NodeCount)
+	if opts.Replay.Node >= nodeCount {
+		return Report[V]{}, fmt.Errorf("replay node %d out of range, the trace has %d nodes", opts.Replay.Node, nodeCount)
+	}
+
+	ids := []int{opts.Replay.Node}
+	if opts.Replay.Node < 0 {
+		ids = ids[:0]
+		for id := range nodeCount {
+			ids = append(ids, id)
+		}
+	}
+
+	if opts.Debug {
+		fmt.Printf("--- Replaying %s (%s, %d of %d nodes) ---\n", opts.Replay.Path, algo.Name, len(ids), nodeCount)
+	}
+
+	engine := NewEngine[T](nodeCount)
+	engine.Topology = header.Config.Topology
+	engine.Adaptive = header.Config.Adaptive
+	engine.Termination = header.Config.Termination
+
+	report := Report[V]{
+		Algorithm: algo.Name,
+		Options:   opts,
+	}
+	initialBlocks := make([][]V, nodeCount)
+	finalBlocks := make([][]V, nodeCount)
+	rounds := make([]int, nodeCount)
+	replays := make([]*nodeReplay[T], len(ids))
+	for i, id := range ids {
+		if replays[i], err = newNodeReplay[T](id, events[id], opts.Debug); err != nil {
+			return Report[V]{}, err
+		}
+		engine.Rows, engine.Cols = replays[i].start.Rows, replays[i].start.Cols
+	}
+	startTime := time.Now()
+
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		r := replays[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			node := r.node()
+			initialBlocks[id] = algo.Values(node.Value)
+
+			finished := make(chan struct{})
+			go func() {
+				defer close(finished)
+				defer func() {
+					if p := recover(); p != nil {
+						r.diverge("panicked: %v", p)
+					}
+				}()
+				algo.Run(node, engine, transport.SendMessage[T], kind.Compare, opts.Debug)
+			}()
+			select {
+			case <-finished:
+				r.finish(node)
+			case <-r.stuck:
+			}
+			finalBlocks[id] = algo.Values(node.Value)
+			rounds[id] = node.Round
+		}()
+	}
+	wg.Wait()
+
+	for _, r := range replays {
+		report.Replay = append(report.Replay, r.result)
+	}
+	if len(ids) == nodeCount {
+		order := make([]int, nodeCount)
+		for id := range order {
+			order[id] = id
+		}
+		if algo.Order != nil {
+			order = algo.Order(nodeCount)
+		}
+		for id := range nodeCount {
+			report.Initial = append(report.Initial, initialBlocks[id]...)
+			report.Final = append(report.Final, finalBlocks[order[id]]...)
+			report.Rounds = max(report.Rounds, rounds[id])
+		}
+		report.Verified = Verify(report.Initial, report.Final, kind.Compare)
+	} else {
+		report.Rounds = rounds[opts.Replay.Node]
+	}
+
+	report.Elapsed = time.Since(startTime)
+	return report, nil
+}
+
+// nodeReplay feeds one node its recorded inputs and checks its outputs.
+type nodeReplay[T any] struct {
+	id     int
+	debug  bool
+	start  TraceEvent
+	end    *TraceEvent
+	events []TraceEvent
+
+	value    T
+	receives map[replayLink][]types.Message[T]
+
+	mu     sync.Mutex
+	sends  map[replayLink][]TraceEvent
+	stuck  chan struct{}
+	result NodeReplay
+}
+
+type replayLink struct {
+	label   types.Direction
+	control bool
+}
+
+// newNodeReplay checks and decodes a node's recorded events, so a malformed
+// or truncated trace is reported before anything runs.
+func newNodeReplay[T any](id int, events []TraceEvent, debug bool) (*nodeReplay[T], error) {
+	if len(events) == 0 || events[0].Kind != TraceStart {
+		return nil, fmt.Errorf("trace has no start event for node %d", id)
+	}
+	r := &nodeReplay[T]{
+		id:       id,
+		debug:    debug,
+		start:    events[0],
+		events:   events[1:],
+		receives: make(map[replayLink][]types.Message[T]),
+		sends:    make(map[replayLink][]TraceEvent),
+		stuck:    make(chan struct{}),
+		result:   NodeReplay{Node: id},
+	}
+	if err := json.Unmarshal(r.start.Message, &r.value); err != nil {
+		return nil, fmt.Errorf("node %d: decoding initial payload: %w", id, err)
+	}
+
+	labels := make(map[types.Direction]bool, len(r.start.Neighbors))
+	for _, nb := range r.start.Neighbors {
+		labels[nb.Label] = true
+	}
+	for i, event := range r.events {
+		if r.end != nil {
+			return nil, fmt.Errorf("node %d: event %d follows the end event", id, event.Seq)
+		}
+		link := replayLink{label: event.Label, control: event.Control}
+		switch event.Kind {
+		case TraceSend, TraceRecv:
+			if !labels[event.Label] {
+				return nil, fmt.Errorf("node %d: event %d is on %q, which is not one of its links", id, event.Seq, event.Label)
+			}
+			var msg types.Message[T]
+			if err := json.Unmarshal(event.Message, &msg); err != nil {
+				return nil, fmt.Errorf("node %d: decoding message %d: %w", id, event.Seq, err)
+			}
+			if event.Kind == TraceSend {
+				r.sends[link] = append(r.sends[link], event)
+			} else {
+				r.receives[link] = append(r.receives[link], msg)
+			}
+		case TraceEnd:
+			r.end = &r.events[i]
+		default:
+			return nil, fmt.Errorf("node %d: event %d has unknown kind %q", id, event.Seq, event.Kind)
+		}
+	}
+	return r, nil
+}
+
+// node rebuilds the node as it was when recording started, with links that
+// read from and check against the trace.
+func (r *nodeReplay[T]) node() *types.Node[T] {
+	node := &types.Node[T]{
+		ID:        r.id,
+		Position:  r.start.Position,
+		TotalNode: r.start.Total,
+		Rank:      r.start.Rank,
+		Value:     r.value,
+		Neighbors: make(map[types.Direction]*types.Neighbor[T]),
+	}
+	for _, nb := range r.start.Neighbors {
+		data, control := replayLink{label: nb.Label}, replayLink{label: nb.Label, control: true}
+		node.Neighbors[nb.Label] = &types.Neighbor[T]{
+			ID:      nb.ID,
+			Label:   nb.Label,
+			Conn:    &replayConn[T]{replay: r, label: nb.Label},
+			Buffer:  &replayReceiver[T]{replay: r, link: data, queue: r.receives[data]},
+			Control: &replayReceiver[T]{replay: r, link: control, queue: r.receives[control]},
+		}
+	}
+	return node
+}
+
+func (r *nodeReplay[T]) diverge(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.result.Divergence == nil {
+		r.result.Divergence = fmt.Errorf(format, args...)
+		if r.debug {
+			fmt.Printf("[Replay] Node %d: DIVERGED: %v\n", r.id, r.result.Divergence)
+		}
+	}
+}
+
+// checkSend compares a message the node sends with the next one recorded
+// on the same link.
+func (r *nodeReplay[T]) checkSend(label types.Direction, p []byte) {
+	var got messageHead
+	if err := json.Unmarshal(p, &got); err != nil {
+		r.diverge("sent an undecodable message on %s: %v", label, err)
+		return
+	}
+	link := replayLink{label: label, control: got.Type == types.MsgTerm}
+
+	r.mu.Lock()
+	queue := r.sends[link]
+	if len(queue) == 0 {
+		r.mu.Unlock()
+		r.diverge("sent a %s message for round %d on %s that was never recorded", got.Type, got.Round, label)
+		return
+	}
+	event := queue[0]
+	r.sends[link] = queue[1:]
+	r.result.Sends++
+	r.mu.Unlock()
+
+	var want messageHead
+	_ = json.Unmarshal(event.Message, &want)
+	if got.Round != want.Round || got.Type != want.Type || got.ReceiverID != want.ReceiverID || got.Sequence != want.Sequence || !sameJSON(got.Body, want.Body) {
+		r.diverge("send #%d on %s: got %s round %d body %s, recorded %s round %d body %s",
+			event.Seq, label, got.Type, got.Round, got.Body, want.Type, want.Round, want.Body)
+		return
+	}
+	if r.debug {
+		fmt.Printf("[Replay] Node %d: #%d sent %s round %d on %s: %s\n", r.id, event.Seq, got.Type, got.Round, label, got.Body)
+	}
+}
+
+// finish compares the node's final state with the recorded one and checks
+// that it sent every recorded data message.
+func (r *nodeReplay[T]) finish(node *types.Node[T]) {
+	r.mu.Lock()
+	var missing []TraceEvent
+	for link, queue := range r.sends {
+		if !link.control && len(queue) > 0 {
+			missing = queue
+		}
+	}
+	r.mu.Unlock()
+	if missing != nil {
+		r.diverge("%d recorded sends on %s never happened, the first for round %d", len(missing), missing[0].Label, missing[0].Round)
+	}
+	if r.end == nil {
+		r.diverge("the recording has no end for this node")
+		return
+	}
+	if final := mustMarshal(node.Value); !sameJSON(final, r.end.Message) {
+		r.diverge("final state %s differs from the recorded %s", final, r.end.Message)
+	} else if node.Round != r.end.Round {
+		r.diverge("finished after round %d, recorded %d", node.Round, r.end.Round)
+	}
+}
+
+// replayConn stands in for a link: it sends nothing and checks every
+// message against the trace.
+type replayConn[T any] struct {
+	net.Conn
+	replay *nodeReplay[T]
+	label  types.Direction
+}
+
+func (c *replayConn[T]) Write(p []byte) (int, error) {
+	c.replay.checkSend(c.label, p)
+	return len(p), nil
+}
+
+func (c *replayConn[T]) Close() error {
+	return nil
+}
+
+// replayReceiver hands out a link's recorded receives in recorded order. A
+// node asking for a round the trace never delivered has diverged; as there
+// is nothing to give it, the node is stuck and the replay moves on.
+type replayReceiver[T any] struct {
+	replay *nodeReplay[T]
+	link   replayLink
+
+	mu    sync.Mutex
+	queue []types.Message[T]
+}
+
+func (b *replayReceiver[T]) GetStepMessage(round int) types.Message[T] {
+	b.mu.Lock()
+	for i, msg := range b.queue {
+		if msg.Round != round {
+			continue
+		}
+		if i > 0 {
+			b.replay.diverge("read round %d on %s before round %d, unlike the recording", round, b.link.label, b.queue[0].Round)
+		}
+		b.queue = append(b.queue[:i:i], b.queue[i+1:]...)
+		b.mu.Unlock()
+
+		b.replay.mu.Lock()
+		b.replay.result.Receives++
+		b.replay.mu.Unlock()
+		if b.replay.debug {
+			body, _ := json.Marshal(msg.Body)
+			fmt.Printf("[Replay] Node %d: received %s round %d on %s: %s\n", b.replay.id, msg.Type, round, b.link.label, body)
+		}
+		return msg
+	}
+	b.mu.Unlock()
+
+	// The termination detector keeps exchanging votes after the algorithm
+	// stops, which the recording misses; a missing control message only
+	// matters once the node has gone off track anyway.
+	if !b.link.control {
+		b.replay.diverge("waited for round %d on %s, which the recording never delivered", round, b.link.label)
+	}
+	if b.replay.diverged() {
+		b.replay.stop()
+	}
+	select {}
+}
+
+func (r *nodeReplay[T]) diverged() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.result.Divergence != nil
+}
+
+// stop gives up on the node; its goroutines stay blocked.
+func (r *nodeReplay[T]) stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	select {
+	case <-r.stuck:
+	default:
+		close(r.stuck)
+	}
+}
+
+// sameJSON compares two encodings ignoring insignificant whitespace, so a
+// trace edited by hand still replays.
+func sameJSON(a, b []byte) bool {
+	var ca, cb bytes.Buffer
+	if json.Compact(&ca, a) != nil || json.Compact(&cb, b) != nil {
+		return bytes.Equal(a, b)
+	}
+	return bytes.Equal(ca.Bytes(), cb.Bytes())
+}
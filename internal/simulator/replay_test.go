@@ -0,0 +1,52 @@
+package simulator
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+func TestNodeReplayRejectsMalformedTraces(t *testing.T) {
+	start := TraceEvent{
+		Kind:      TraceStart,
+		Message:   json.RawMessage(`3`),
+		Neighbors: []TraceNeighbor{{Label: types.Right, ID: 1}},
+	}
+	send := TraceEvent{Seq: 1, Kind: TraceSend, Label: types.Right, Message: json.RawMessage(`{"round":0,"type":"DATA","body":3}`)}
+	end := TraceEvent{Seq: 2, Kind: TraceEnd, Message: json.RawMessage(`3`)}
+
+	if _, err := newNodeReplay[int](0, []TraceEvent{start, send, end}, false); err != nil {
+		t.Fatalf("Valid trace rejected: %v", err)
+	}
+
+	badStart := start
+	badStart.Message = json.RawMessage(`"three"`)
+	unknownLink := send
+	unknownLink.Label = types.Left
+	truncated := send
+	truncated.Message = json.RawMessage(`{"round":0,"ty`)
+	unknownKind := send
+	unknownKind.Kind = "jump"
+
+	tests := []struct {
+		name   string
+		events []TraceEvent
+		want   string
+	}{
+		{"no events", nil, "no start event"},
+		{"no start", []TraceEvent{send, end}, "no start event"},
+		{"bad initial payload", []TraceEvent{badStart, send, end}, "initial payload"},
+		{"unknown link", []TraceEvent{start, unknownLink, end}, "not one of its links"},
+		{"truncated message", []TraceEvent{start, truncated, end}, "decoding message"},
+		{"unknown kind", []TraceEvent{start, unknownKind, end}, "unknown kind"},
+		{"event after end", []TraceEvent{start, end, send}, "follows the end"},
+	}
+	for _, tt := range tests {
+		_, err := newNodeReplay[int](0, tt.events, false)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got error %v, expected one mentioning %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package simulator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/transport"
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+// DiscoverRing counts the nodes of a ring, which has no endpoints to start
+// from. The ring is wired both ways, but discovery only sends to the right
+// and reads from the left. Nodes first elect the smallest ID as leader
+// (Chang-Roberts: IDs travel rightwards and are dropped by any smaller node),
+// then the leader sends a counter around the ring and finally broadcasts the
+// total. Every node returns the total and its distance from the leader.
+//
+// Discovery messages on a link use rounds -1, -2, ... in sending order, so
+// they never collide in the RoundBuffer with each other or with the
+// algorithm's rounds.
+func DiscoverRing[T any](n *types.Node[T]) (total, rank int, err error) {
+	left, right := n.Neighbor(types.Left), n.Neighbor(types.Right)
+	if left == nil || right == nil {
+		return -1, -1, fmt.Errorf("ring node %d is missing a neighbor", n.ID)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	sent, received := 0, 0
+	send := func(t types.MessageType, value int) error {
+		sent++
+		msg := types.Message[T]{Type: t, SenderID: n.ID, ReceiverID: right.ID, Round: -sent, Sequence: uint64(value)}
+		return transport.SendMessage(right.Conn, msg)
+	}
+
+	if err := send(types.MsgElect, n.ID); err != nil {
+		return -1, -1, err
+	}
+
+	leader := false
+	for {
+		received++
+		msg := left.Buffer.GetStepMessage(-received)
+		value := int(msg.Sequence)
+
+		switch msg.Type {
+		case types.MsgElect:
+			if value == n.ID {
+				leader = true
+				err = send(types.MsgCount, 1)
+			} else if value < n.ID {
+				err = send(types.MsgElect, value)
+			}
+		case types.MsgCount:
+			if leader {
+				err = send(types.MsgInit, value)
+			} else {
+				rank = value
+				err = send(types.MsgCount, value+1)
+			}
+		case types.MsgInit:
+			if !leader {
+				err = send(types.MsgInit, value)
+			}
+			n.TotalNode = value
+			return value, rank, err
+		}
+		if err != nil {
+			return -1, -1, err
+		}
+	}
+}
@@ -0,0 +1,688 @@
+package simulator
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/transport"
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+type RunOptions struct {
+	Config    types.Config
+	Debug     bool
+	Benchmark bool
+	Transport transport.Options
+	Speed     SpeedProfile
+	Schedule  Schedule
+
+	// Clocks gives every node a logical clock of this mode (see
+	// transport.Clock) and checks the run's rounds for causality.
+	Clocks transport.ClockMode
+
+	// Trace records every message to this file; Replay re-executes a
+	// recorded run instead of starting a new one.
+	Trace  string
+	Replay ReplayOptions
+
+	// Snapshots collects every node's state at the end of each round into
+	// Report.Snapshots. Visualize animates them in the terminal, one frame
+	// every VisualizeDelay, instead of printing them.
+	Snapshots      bool
+	Visualize      bool
+	VisualizeDelay time.Duration
+
+	// Dashboard serves a live view of the run over HTTP on this address
+	// (see Dashboard).
+	Dashboard string
+
+	// Step pauses the run at every round boundary and reads debugger
+	// commands from stdin (see Debugger).
+	Step bool
+
+	// ChromeTrace writes the nodes' timelines to this file (see Timeline),
+	// SVG a space-time diagram of the run (see SpaceTime).
+	ChromeTrace string
+	SVG         string
+}
+
+// Algorithm describes how the runner builds, runs and reads back the nodes
+// of one sorting algorithm over values of type V. Order lists the node IDs
+// in the order their values form the sorted output; nil means ID order.
+// State optionally describes a node's algorithm state besides its values
+// for round snapshots, and Highlight how -visualize draws the node.
+type Algorithm[V, T any] struct {
+	Name      string
+	Payload   func(values []V) T
+	Values    func(payload T) []V
+	Order     func(nodeCount int) []int
+	State     func(n *types.Node[T]) string
+	Highlight func(n *types.Node[T]) Highlight
+	Run       func(n *types.Node[T], engine *SimulatorEngine[T], sendFunc func(net.Conn, types.Message[T]) error, compare func(a, b V) int, debug bool)
+}
+
+type Report[V any] struct {
+	Algorithm string
+	Options   RunOptions
+	Initial   []V
+	Final     []V
+	Verified  error
+	Stability *Stability
+	Causality *Causality
+	Replay    []NodeReplay
+	Snapshots []Snapshot[V]
+	Rounds    int
+	Elapsed   time.Duration
+	Transport transport.StatsSnapshot
+}
+
+// Stability is reported for element types that remember their original
+// position: Inversions counts adjacent equal-key pairs in the final array
+// that appear in the opposite of their initial order.
+type Stability struct {
+	Inversions int
+	First      int
+}
+
+// Causality is reported when messages carry logical clocks: Messages data
+// messages were checked and Violation describes the first node that sent a
+// round before it had received all messages of an earlier one.
+type Causality struct {
+	Messages  int
+	Violation error
+}
+
+// ParseFlags reads the command line of the binary running algorithm. Only
+// the flags the algorithm supports are registered, so -h lists just those.
+func ParseFlags(algorithm types.AlgorithmType) (RunOptions, error) {
+	nodeCount := flag.Uint("node-count", 10, "Number of Nodes")
+	blockSize := flag.Uint("block-size", 1, "Number of values held by each node")
+	inputTypeStr := flag.String("input-type", "random", "Input type (random, sorted, reverse, nearly)")
+	valueTypeStr := flag.String("value-type", "int", "Element type (int, float, string, record)")
+	debug := flag.Bool("debug", false, "Enable verbose logging")
+	benchmark := flag.Bool("benchmark", false, "Enable benchmarking metrics")
+	noDelay := flag.Bool("nodelay", true, "Set TCP_NODELAY on neighbor connections")
+	batch := flag.Bool("batch", false, "Hold each link's messages back and write them out once per round")
+	compressStr := flag.String("compress", "none", "Per-message compression (none, gzip, flate)")
+	latencyStr := flag.String("latency", "none", "Per-link latency model (uniform:MIN,MAX, normal:MEAN,STDDEV, file:PATH)")
+	bandwidthStr := flag.String("bandwidth", "", "Per-link bandwidth cap in bytes/s, e.g. 512KB (empty for unlimited)")
+	clocksStr := flag.String("clocks", "none", "Logical clocks stamped on messages (none, lamport, vector)")
+	speedStr := flag.String("node-speed", "none", "Per-node compute delay (uniform:D, slow:IDS@D, stragglers:K@D, jitter:MAX, joined with +)")
+	tracePath := flag.String("trace", "", "Record every message sent and received to this file")
+	replayPath := flag.String("replay", "", "Replay a trace recorded with -trace instead of running")
+	replayNode := flag.Int("replay-node", -1, "Replay only this node of the trace (-1 for all)")
+	chromeTrace := flag.String("chrome-trace", "", "Write node timelines in Chrome Trace Event format to this file")
+	svgPath := flag.String("svg", "", "Write a space-time diagram of the run to this SVG file")
+	snapshots := flag.Bool("snapshots", false, "Print every node's state at the end of each round")
+	visualize := flag.Bool("visualize", false, "Animate the array round by round as a terminal bar chart")
+	visualizeDelay := flag.Duration("visualize-delay", 500*time.Millisecond, "Time between -visualize frames")
+	step := flag.Bool("step", false, "Pause at every round boundary and read debugger commands from stdin")
+	dashboardAddr := flag.String("dashboard", "", "Serve a live dashboard of the run on this address, e.g. localhost:8080")
+
+	var meshRows uint
+	var networkStr string
+	var adaptive, gatherRoot, stable bool
+	terminationStr := string(types.MessageTermination)
+	switch algorithm {
+	case types.OddEven:
+		flag.BoolVar(&adaptive, "adaptive", false, "Stop once no node has changed for two consecutive rounds")
+		flag.StringVar(&terminationStr, "termination", terminationStr, "How -adaptive nodes agree to stop (message, shared)")
+	case types.Shearsort:
+		flag.UintVar(&meshRows, "mesh-rows", 0, "Rows of the mesh topology (0 picks the most square shape)")
+	case types.NetworkSort:
+		flag.StringVar(&networkStr, "network", "batcher", "Comparator network to run (batcher, bitonic, oddeven, file:PATH)")
+	case types.TreeMerge:
+		flag.BoolVar(&gatherRoot, "gather-root", false, "Leave the result at the root instead of redistributing it")
+	case types.Sasaki:
+		flag.BoolVar(&stable, "stable", false, "Tag block elements with their initial slot so equal values keep their order")
+	}
+	flag.Parse()
+
+	// A replay runs with the recorded configuration, whatever the other
+	// flags say.
+	if *replayPath != "" {
+		header, err := ReadTraceHeader(*replayPath)
+		if err != nil {
+			return RunOptions{}, err
+		}
+		if header.Config.Algorithm != algorithm {
+			return RunOptions{}, fmt.Errorf("trace %s was recorded by %s", *replayPath, header.Algorithm)
+		}
+		if *replayNode >= int(header.Config.NodeCount) {
+			return RunOptions{}, fmt.Errorf("replay node %d out of range, the trace has %d nodes", *replayNode, header.Config.NodeCount)
+		}
+		if *replayNode >= 0 && header.Config.Adaptive && header.Config.Termination == types.SharedTermination {
+			return RunOptions{}, fmt.Errorf("shared termination can only be replayed for the whole system")
+		}
+		return RunOptions{
+			Config:    header.Config,
+			Debug:     *debug,
+			Benchmark: *benchmark,
+			Schedule:  header.Schedule,
+			Replay:    ReplayOptions{Path: *replayPath, Node: *replayNode},
+		}, nil
+	}
+
+	var inputType types.InputType
+	switch strings.ToLower(*inputTypeStr) {
+	case "random":
+		inputType = types.Random
+	case "sorted":
+		inputType = types.Sorted
+	case "reverse":
+		inputType = types.Reverse
+	case "nearly":
+		inputType = types.NearlySorted
+	default:
+		return RunOptions{}, fmt.Errorf("invalid input type %q", *inputTypeStr)
+	}
+
+	var valueType types.ValueType
+	switch strings.ToLower(*valueTypeStr) {
+	case "int":
+		valueType = types.IntValues
+	case "float":
+		valueType = types.FloatValues
+	case "string":
+		valueType = types.StringValues
+	case "record":
+		valueType = types.RecordValues
+	default:
+		return RunOptions{}, fmt.Errorf("invalid value type %q", *valueTypeStr)
+	}
+
+	compression, err := transport.ParseCompression(*compressStr)
+	if err != nil {
+		return RunOptions{}, err
+	}
+	latency, err := transport.ParseLatency(*latencyStr)
+	if err != nil {
+		return RunOptions{}, err
+	}
+	bandwidth, err := transport.ParseBandwidth(*bandwidthStr)
+	if err != nil {
+		return RunOptions{}, err
+	}
+	clockMode, err := transport.ParseClockMode(*clocksStr)
+	if err != nil {
+		return RunOptions{}, err
+	}
+	speed, err := ParseSpeedProfile(*speedStr, int(*nodeCount))
+	if err != nil {
+		return RunOptions{}, err
+	}
+
+	topology := algorithm.Topology()
+	if topology == types.Ring && *nodeCount < 3 {
+		return RunOptions{}, fmt.Errorf("a ring needs at least 3 nodes")
+	}
+	rows := meshRows
+	if topology == types.Mesh {
+		if rows == 0 {
+			rows = 1
+			for r := uint(1); r*r <= *nodeCount; r++ {
+				if *nodeCount%r == 0 {
+					rows = r
+				}
+			}
+		}
+		if *nodeCount%rows != 0 {
+			return RunOptions{}, fmt.Errorf("%d mesh rows do not divide %d nodes", rows, *nodeCount)
+		}
+	}
+	if topology == types.Hypercube && (*nodeCount == 0 || *nodeCount&(*nodeCount-1) != 0) {
+		return RunOptions{}, fmt.Errorf("a hypercube needs a power-of-two node count, got %d", *nodeCount)
+	}
+
+	termination := types.TerminationMode(strings.ToLower(terminationStr))
+	if termination != types.MessageTermination && termination != types.SharedTermination {
+		return RunOptions{}, fmt.Errorf("invalid termination mode %q", terminationStr)
+	}
+
+	var schedule Schedule
+	if algorithm == types.NetworkSort {
+		schedule, err = ParseSchedule(networkStr, int(*nodeCount))
+		if err != nil {
+			return RunOptions{}, err
+		}
+	}
+
+	return RunOptions{
+		Config: types.Config{
+			NodeCount: *nodeCount,
+			BlockSize: max(*blockSize, 1),
+			InputType: inputType,
+			ValueType: valueType,
+			Algorithm: algorithm,
+			Topology:  topology,
+			Rows:      rows,
+
+			GatherRoot:  gatherRoot,
+			Stable:      stable,
+			Adaptive:    adaptive,
+			Termination: termination,
+		},
+		Debug:     *debug,
+		Benchmark: *benchmark,
+		Transport: transport.Options{
+			NoDelay:     *noDelay,
+			Batch:       *batch,
+			Compression: compression,
+			Latency:     latency,
+			Bandwidth:   bandwidth,
+		},
+		Clocks:   clockMode,
+		Speed:    speed,
+		Schedule: schedule,
+		Trace:    *tracePath,
+
+		Snapshots:      *snapshots || *visualize,
+		Visualize:      *visualize,
+		VisualizeDelay: *visualizeDelay,
+
+		Step:      *step,
+		Dashboard: *dashboardAddr,
+
+		ChromeTrace: *chromeTrace,
+		SVG:         *svgPath,
+	}, nil
+}
+
+func Run[V, T any](opts RunOptions, algo Algorithm[V, T], kind ValueKind[V]) (Report[V], error) {
+	if opts.Replay.Path != "" {
+		return replayTrace(opts, algo, kind)
+	}
+
+	nodeCount := int(opts.Config.NodeCount)
+	if opts.Transport.Stats == nil {
+		opts.Transport.Stats = &transport.Stats{}
+	}
+
+	var recorder *TraceRecorder
+	if opts.Trace != "" {
+		var err error
+		header := TraceHeader{Algorithm: algo.Name, Config: opts.Config, Schedule: opts.Schedule, Started: time.Now()}
+		if recorder, err = NewTraceRecorder(opts.Trace, header, nodeCount); err != nil {
+			return Report[V]{}, err
+		}
+	}
+
+	if opts.Debug {
+		fmt.Printf("--- Distributed Sorting Simulator (%s) ---\n", algo.Name)
+		fmt.Printf("Nodes: %d | Values: %s | Block Size: %d | Batch: %t | NoDelay: %t\n", nodeCount, kind.Name, opts.Config.BlockSize, opts.Transport.Batch, opts.Transport.NoDelay)
+	}
+
+	engine := NewEngine[T](nodeCount)
+	engine.Speed = opts.Speed
+	engine.Adaptive = opts.Config.Adaptive
+	engine.Termination = opts.Config.Termination
+	if opts.ChromeTrace != "" {
+		engine.Timeline = NewTimeline(algo.Name, nodeCount)
+	}
+	label := func(payload T) string {
+		values := algo.Values(payload)
+		if len(values) == 1 {
+			return fmt.Sprint(values[0])
+		}
+		return fmt.Sprint(values)
+	}
+	if opts.SVG != "" {
+		engine.SpaceTime = NewSpaceTime(algo.Name, nodeCount, label)
+	}
+	if opts.Step {
+		engine.Debugger = NewDebugger(algo.Name, nodeCount, os.Stdin, label, algo.State)
+	}
+
+	order := make([]int, nodeCount)
+	for id := range order {
+		order[id] = id
+	}
+	if algo.Order != nil {
+		order = algo.Order(nodeCount)
+	}
+	var dashboard *Dashboard[V, T]
+	if opts.Dashboard != "" {
+		var err error
+		if dashboard, err = NewDashboard(opts.Dashboard, algo.Name, order, algo, kind.Compare, opts.Transport.Stats); err != nil {
+			if recorder != nil {
+				recorder.Close()
+			}
+			return Report[V]{}, err
+		}
+		engine.dashboard = dashboard
+		fmt.Printf("Dashboard: %s\n", dashboard.URL())
+	}
+	var snapshots *snapshots[V, T]
+	if opts.Snapshots {
+		snapshots = newSnapshots(nodeCount, algo)
+		engine.snapshots = snapshots
+	}
+	var hooks []NeighborHook[T]
+	if recorder != nil {
+		hooks = append(hooks, traceHook[T](recorder))
+	}
+	if engine.Timeline != nil {
+		hooks = append(hooks, timelineHook[T](engine.Timeline))
+	}
+	if engine.SpaceTime != nil {
+		hooks = append(hooks, engine.SpaceTime.hook)
+	}
+	if engine.Debugger != nil {
+		hooks = append(hooks, engine.Debugger.hook)
+	}
+
+	// Every node owns its logical clock; the runner only reads them back to
+	// validate the run once the nodes are done.
+	var clocks []*transport.Clock
+	if opts.Clocks != "" && opts.Clocks != transport.NoClocks {
+		clocks = make([]*transport.Clock, nodeCount)
+		for id := range clocks {
+			clocks[id] = transport.NewClock(opts.Clocks, id, nodeCount)
+		}
+	}
+	var wg sync.WaitGroup
+
+	report := Report[V]{
+		Algorithm: algo.Name,
+		Options:   opts,
+	}
+	initialBlocks := make([][]V, nodeCount)
+	finalBlocks := make([][]V, nodeCount)
+	rounds := make([]int, nodeCount)
+	links := make([]*NodeLinks, nodeCount)
+	startTime := time.Now()
+
+	for i := 0; i < nodeCount; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			if engine.Debugger != nil {
+				defer engine.Debugger.finish(id)
+			}
+
+			node := &types.Node[T]{
+				ID:        id,
+				TotalNode: nodeCount,
+			}
+
+			if opts.Config.Topology != types.Line {
+				node.Position = types.Middle
+			} else if id == 0 {
+				node.Position = types.Head
+			} else if id == nodeCount-1 {
+				node.Position = types.Tail
+			} else {
+				node.Position = types.Middle
+			}
+
+			nodeOpts := opts.Transport
+			if clocks != nil {
+				nodeOpts.Clock = clocks[id]
+			}
+			nl, err := SetupNode(node, opts.Config, nodeOpts, opts.Debug, hooks...)
+			if err != nil {
+				if opts.Debug {
+					fmt.Printf("Error setup node %d: %v\n", id, err)
+				}
+				return
+			}
+			links[id] = nl
+
+			time.Sleep(100 * time.Millisecond)
+
+			if err := engine.InitialSetup(node, opts.Config, opts.Debug); err != nil {
+				if opts.Debug {
+					fmt.Printf("Error discovery node %d: %v\n", id, err)
+				}
+				return
+			}
+
+			block := kind.Block(id*int(opts.Config.BlockSize), GenerateInitialBlock(id, opts.Config))
+			node.Value = algo.Payload(block)
+
+			initialBlocks[id] = block
+
+			if recorder != nil {
+				startTrace(recorder, node, engine)
+			}
+			if engine.Timeline != nil {
+				beginRounds(engine.Timeline, node)
+			}
+			if engine.SpaceTime != nil {
+				beginSpaceTime(engine.SpaceTime, node)
+			}
+			if snapshots != nil {
+				snapshots.begin(node)
+			}
+			if dashboard != nil {
+				dashboard.begin(node)
+			}
+			if engine.Debugger != nil {
+				engine.Debugger.begin(node)
+			}
+			engine.compute(node)
+			algo.Run(node, engine, transport.SendMessage[T], kind.Compare, opts.Debug)
+			FlushLinks(node)
+			if recorder != nil {
+				endTrace(recorder, node)
+			}
+
+			if dashboard != nil {
+				dashboard.finish(node)
+			}
+
+			finalBlocks[id] = algo.Values(node.Value)
+			rounds[id] = node.Round
+		}(i)
+	}
+
+	wg.Wait()
+	CloseLinks(links)
+
+	if recorder != nil {
+		if err := recorder.Close(); err != nil {
+			fmt.Printf("Error writing trace %s: %v\n", opts.Trace, err)
+		}
+	}
+
+	if engine.Timeline != nil {
+		if err := engine.Timeline.WriteFile(opts.ChromeTrace); err != nil {
+			fmt.Printf("Error writing Chrome trace %s: %v\n", opts.ChromeTrace, err)
+		}
+	}
+	if engine.SpaceTime != nil {
+		if err := engine.SpaceTime.WriteFile(opts.SVG); err != nil {
+			fmt.Printf("Error writing space-time diagram %s: %v\n", opts.SVG, err)
+		}
+	}
+	if dashboard != nil {
+		if err := dashboard.Close(); err != nil {
+			fmt.Printf("Error stopping dashboard: %v\n", err)
+		}
+	}
+	for id := range nodeCount {
+		report.Initial = append(report.Initial, initialBlocks[id]...)
+		report.Final = append(report.Final, finalBlocks[order[id]]...)
+		report.Rounds = max(report.Rounds, rounds[id])
+	}
+	report.Verified = Verify(report.Initial, report.Final, kind.Compare)
+	if snapshots != nil {
+		report.Snapshots = snapshots.matrix(order, kind.Compare)
+	}
+	if kind.Index != nil {
+		if report.Verified == nil {
+			report.Verified = verifyIndexes(report.Initial, report.Final, kind.Compare, kind.Index)
+		}
+		inversions, first := CheckStability(report.Final, kind.Compare, kind.Index)
+		report.Stability = &Stability{Inversions: inversions, First: first}
+	}
+
+	if clocks != nil {
+		messages, err := transport.ValidateClocks(clocks)
+		report.Causality = &Causality{Messages: messages, Violation: err}
+	}
+
+	report.Elapsed = time.Since(startTime)
+	report.Transport = opts.Transport.Stats.Snapshot()
+	return report, nil
+}
+
+func (r Report[V]) Print() {
+	nodeCount := int(r.Options.Config.NodeCount)
+	if r.Replay != nil {
+		fmt.Printf("\n--- Replay of %s ---\n", r.Options.Replay.Path)
+		for _, nr := range r.Replay {
+			if nr.Divergence == nil {
+				fmt.Printf("Node %d: matches the recording (%d sends, %d receives)\n", nr.Node, nr.Sends, nr.Receives)
+			} else {
+				fmt.Printf("Node %d: DIVERGED after %d sends, %d receives: %v\n", nr.Node, nr.Sends, nr.Receives, nr.Divergence)
+			}
+		}
+		if r.Final == nil {
+			fmt.Println("Replay Complete.")
+			return
+		}
+	}
+	if r.Snapshots != nil && r.Options.Visualize {
+		animate(r.Algorithm, r.Snapshots, r.Options.VisualizeDelay)
+	}
+	if len(r.Initial) <= 100 {
+		fmt.Printf("\n--- Results (N=%d) ---\n", nodeCount)
+		fmt.Printf("Initial: %v\n", r.Initial)
+		fmt.Printf("Final:   %v\n", r.Final)
+	}
+	if r.Snapshots != nil && !r.Options.Visualize {
+		printSnapshots(r.Snapshots)
+	}
+	if r.Verified != nil {
+		fmt.Printf("Verification FAILED: %v\n", r.Verified)
+	}
+	if r.Stability != nil {
+		if r.Stability.Inversions == 0 {
+			fmt.Println("Stability: stable")
+		} else {
+			fmt.Printf("Stability: UNSTABLE (%d equal-key pairs out of original order, first at position %d)\n", r.Stability.Inversions, r.Stability.First)
+		}
+	}
+	if r.Causality != nil {
+		if r.Causality.Violation == nil {
+			fmt.Printf("Causality: consistent (%s clocks, %d data messages)\n", r.Options.Clocks, r.Causality.Messages)
+		} else {
+			fmt.Printf("Causality: VIOLATED (%v)\n", r.Causality.Violation)
+		}
+	}
+
+	if r.Options.Trace != "" {
+		fmt.Printf("Trace: %s\n", r.Options.Trace)
+	}
+	if r.Options.ChromeTrace != "" {
+		fmt.Printf("Chrome trace: %s\n", r.Options.ChromeTrace)
+	}
+	if r.Options.SVG != "" {
+		fmt.Printf("Space-time diagram: %s\n", r.Options.SVG)
+	}
+
+	if !r.Options.Benchmark {
+		fmt.Println("Simulation Complete.")
+		return
+	}
+
+	stats := r.Transport
+	fmt.Printf("\n--- Benchmark Results ---\n")
+	fmt.Printf("Algorithm: %s\n", r.Algorithm)
+	fmt.Printf("Nodes: %d\n", nodeCount)
+	fmt.Printf("Values: %s\n", r.Options.Config.ValueType)
+	if r.Options.Config.BlockSize > 1 {
+		fmt.Printf("Block Size: %d (%d values)\n", r.Options.Config.BlockSize, len(r.Final))
+	}
+	if r.Options.Config.Topology == types.Mesh {
+		fmt.Printf("Topology: mesh %dx%d\n", r.Options.Config.Rows, nodeCount/int(r.Options.Config.Rows))
+	} else {
+		fmt.Printf("Topology: %s\n", r.Options.Config.Topology)
+	}
+	if r.Options.Config.Adaptive {
+		fmt.Printf("Rounds: %d (adaptive, %s termination, %d of %d saved)\n", r.Rounds, r.Options.Config.Termination, nodeCount-r.Rounds, nodeCount)
+	} else {
+		fmt.Printf("Rounds: %d\n", r.Rounds)
+	}
+	fmt.Printf("Time: %v\n", r.Elapsed)
+	fmt.Printf("Transport: nodelay=%t batch=%t compress=%v\n", r.Options.Transport.NoDelay, r.Options.Transport.Batch, r.Options.Transport.Compression)
+	if r.Options.Transport.Latency != nil || r.Options.Transport.Bandwidth > 0 {
+		fmt.Printf("Network: latency=%v bandwidth=%s\n", r.Options.Transport.Latency, formatBandwidth(r.Options.Transport.Bandwidth))
+	}
+	if r.Options.Speed != nil {
+		fmt.Printf("Node speeds: %v\n", r.Options.Speed)
+	}
+	if r.Options.Schedule.Rounds != nil {
+		fmt.Printf("Comparator network: %v\n", r.Options.Schedule)
+	}
+
+	msgsPerWrite := 0.0
+	if stats.Writes > 0 {
+		msgsPerWrite = float64(stats.Messages) / float64(stats.Writes)
+	}
+	fmt.Printf("Messages: %d | Writes: %d (%.2f msgs/write) | Bytes: %d\n", stats.Messages, stats.Writes, msgsPerWrite, stats.Bytes)
+	if r.Options.Transport.Compression != transport.NoCompression && stats.RawBytes > 0 {
+		fmt.Printf("Payload: %d raw bytes -> %d sent (%.1f%%), %d/%d messages compressed\n",
+			stats.RawBytes, stats.SentBytes, 100*float64(stats.SentBytes)/float64(stats.RawBytes), stats.Compressed, stats.Messages)
+	}
+	fmt.Printf("Throughput: %.0f msgs/s\n", float64(stats.Messages)/r.Elapsed.Seconds())
+	fmt.Printf("Latency: mean %v, max %v\n", stats.MeanLatency, stats.MaxLatency)
+}
+
+func formatBandwidth(bytesPerSec float64) string {
+	switch {
+	case bytesPerSec <= 0:
+		return "unlimited"
+	case bytesPerSec >= 1<<20:
+		return fmt.Sprintf("%.1fMB/s", bytesPerSec/(1<<20))
+	case bytesPerSec >= 1<<10:
+		return fmt.Sprintf("%.1fKB/s", bytesPerSec/(1<<10))
+	default:
+		return fmt.Sprintf("%.0fB/s", bytesPerSec)
+	}
+}
+
+// Verify checks that final is initial in ascending order under compare.
+func Verify[V any](initial, final []V, compare func(a, b V) int) error {
+	if len(initial) != len(final) {
+		return fmt.Errorf("expected %d values, got %d", len(initial), len(final))
+	}
+	for i := 1; i < len(final); i++ {
+		if compare(final[i-1], final[i]) > 0 {
+			return fmt.Errorf("out of order at position %d: %v > %v", i, final[i-1], final[i])
+		}
+	}
+
+	expected := slices.Clone(initial)
+	slices.SortStableFunc(expected, compare)
+	for i := range expected {
+		if compare(expected[i], final[i]) != 0 {
+			return fmt.Errorf("position %d holds %v, expected %v", i, final[i], expected[i])
+		}
+	}
+	return nil
+}
+
+// verifyIndexes checks that every original element arrived exactly once and
+// still carries the key it started with.
+func verifyIndexes[V any](initial, final []V, compare func(a, b V) int, index func(v V) int) error {
+	seen := make([]bool, len(initial))
+	for i, v := range final {
+		idx := index(v)
+		if idx < 0 || idx >= len(initial) || seen[idx] {
+			return fmt.Errorf("position %d holds element with original index %d more than once or out of range", i, idx)
+		}
+		seen[idx] = true
+		if compare(initial[idx], v) != 0 {
+			return fmt.Errorf("element %d changed from %v to %v", idx, initial[idx], v)
+		}
+	}
+	return nil
+}
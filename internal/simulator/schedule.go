@@ -0,0 +1,213 @@
+package simulator
+
+import (
+	"bufio"
+	"fmt"
+	"math/bits"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Comparator compare-exchanges the values of two nodes: Min ends up with the
+// smaller value, Max with the larger.
+type Comparator struct {
+	Min, Max int
+}
+
+// Schedule is a comparator network split into rounds; no node appears twice
+// in a round, so every round's comparators run in parallel.
+type Schedule struct {
+	Name   string
+	Rounds [][]Comparator
+}
+
+func (s Schedule) String() string {
+	return fmt.Sprintf("%s(%d rounds, %d comparators)", s.Name, len(s.Rounds), s.Comparators())
+}
+
+func (s Schedule) Comparators() int {
+	total := 0
+	for _, round := range s.Rounds {
+		total += len(round)
+	}
+	return total
+}
+
+// Partner returns the node that id is compared with in the given round and
+// whether id takes the smaller value, or -1 if id is idle.
+func (s Schedule) Partner(id, round int) (partner int, takeMin bool) {
+	for _, c := range s.Rounds[round] {
+		switch id {
+		case c.Min:
+			return c.Max, true
+		case c.Max:
+			return c.Min, false
+		}
+	}
+	return -1, false
+}
+
+// ParseSchedule builds the comparator network for n nodes from a spec:
+// "batcher" (odd-even merge sort), "bitonic", "oddeven" (transposition) or
+// "file:PATH".
+func ParseSchedule(spec string, n int) (Schedule, error) {
+	kind, path, _ := strings.Cut(spec, ":")
+
+	var s Schedule
+	var err error
+	switch strings.ToLower(kind) {
+	case "", "batcher":
+		s = padded("batcher", n, batcherRounds)
+	case "bitonic":
+		s = padded("bitonic", n, bitonicRounds)
+	case "oddeven":
+		s = oddEvenSchedule(n)
+	case "file":
+		s, err = loadSchedule(path)
+	default:
+		return Schedule{}, fmt.Errorf("unknown comparator network %q", kind)
+	}
+	if err != nil {
+		return Schedule{}, err
+	}
+	return s, s.validate(n)
+}
+
+func (s Schedule) validate(n int) error {
+	for r, round := range s.Rounds {
+		used := make(map[int]bool)
+		for _, c := range round {
+			for _, id := range []int{c.Min, c.Max} {
+				if id < 0 || id >= n {
+					return fmt.Errorf("%s round %d: node %d out of range for %d nodes", s.Name, r, id, n)
+				}
+				if used[id] {
+					return fmt.Errorf("%s round %d: node %d appears twice", s.Name, r, id)
+				}
+				used[id] = true
+			}
+			if c.Min == c.Max {
+				return fmt.Errorf("%s round %d: node %d compared with itself", s.Name, r, c.Min)
+			}
+		}
+	}
+	return nil
+}
+
+// padded generates a network for the next power of two and drops every
+// comparator that touches a node beyond n. Both generators only ever move
+// the smaller value to the lower index, so the missing nodes behave like
+// +infinity and never take part.
+func padded(name string, n int, generate func(size int) [][]Comparator) Schedule {
+	size := 1
+	if n > 1 {
+		size = 1 << bits.Len(uint(n-1))
+	}
+	s := Schedule{Name: name}
+	for _, round := range generate(size) {
+		var kept []Comparator
+		for _, c := range round {
+			if c.Max < n {
+				kept = append(kept, c)
+			}
+		}
+		if len(kept) > 0 {
+			s.Rounds = append(s.Rounds, kept)
+		}
+	}
+	return s
+}
+
+// batcherRounds is Batcher's odd-even merge sort: for merge size 2p and
+// distance k, compare i with i+k when both lie in the same 2p block.
+func batcherRounds(size int) [][]Comparator {
+	var rounds [][]Comparator
+	for p := 1; p < size; p <<= 1 {
+		for k := p; k >= 1; k >>= 1 {
+			var round []Comparator
+			for j := k % p; j+k < size; j += 2 * k {
+				for i := 0; i < k && i+j+k < size; i++ {
+					if (i+j)/(2*p) == (i+j+k)/(2*p) {
+						round = append(round, Comparator{Min: i + j, Max: i + j + k})
+					}
+				}
+			}
+			rounds = append(rounds, round)
+		}
+	}
+	return rounds
+}
+
+// bitonicRounds is bitonic sort with every comparator ascending: merging two
+// sorted blocks of p starts by comparing mirrored positions, which reverses
+// the second block in effect, and continues with half-cleaners.
+func bitonicRounds(size int) [][]Comparator {
+	var rounds [][]Comparator
+	for p := 1; p < size; p <<= 1 {
+		var round []Comparator
+		for i := 0; i < size; i++ {
+			if i&p == 0 {
+				round = append(round, Comparator{Min: i, Max: i ^ (2*p - 1)})
+			}
+		}
+		rounds = append(rounds, round)
+
+		for k := p / 2; k >= 1; k >>= 1 {
+			round = nil
+			for i := 0; i < size; i++ {
+				if i&k == 0 {
+					round = append(round, Comparator{Min: i, Max: i + k})
+				}
+			}
+			rounds = append(rounds, round)
+		}
+	}
+	return rounds
+}
+
+// oddEvenSchedule is odd-even transposition sort, the network RunOddEven
+// follows: round r compares i with i+1 for every i of the round's parity.
+func oddEvenSchedule(n int) Schedule {
+	s := Schedule{Name: "oddeven"}
+	for r := 0; r < n; r++ {
+		var round []Comparator
+		for i := r % 2; i+1 < n; i += 2 {
+			round = append(round, Comparator{Min: i, Max: i + 1})
+		}
+		s.Rounds = append(s.Rounds, round)
+	}
+	return s
+}
+
+// loadSchedule reads one round per line, each a space-separated list of
+// "a,b" comparators sending the smaller value to node a. Blank lines and
+// lines starting with # are skipped.
+func loadSchedule(path string) (Schedule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Schedule{}, err
+	}
+	defer f.Close()
+
+	s := Schedule{Name: path}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var round []Comparator
+		for _, field := range strings.Fields(text) {
+			a, b, ok := strings.Cut(field, ",")
+			minID, err1 := strconv.Atoi(a)
+			maxID, err2 := strconv.Atoi(b)
+			if !ok || err1 != nil || err2 != nil {
+				return Schedule{}, fmt.Errorf("%s:%d: invalid comparator %q", path, line, field)
+			}
+			round = append(round, Comparator{Min: minID, Max: maxID})
+		}
+		s.Rounds = append(s.Rounds, round)
+	}
+	return s, scanner.Err()
+}
@@ -0,0 +1,71 @@
+package simulator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// apply runs the schedule's comparators over values, smaller value to Min.
+func apply(s Schedule, values []int) {
+	for _, round := range s.Rounds {
+		for _, c := range round {
+			if values[c.Min] > values[c.Max] {
+				values[c.Min], values[c.Max] = values[c.Max], values[c.Min]
+			}
+		}
+	}
+}
+
+// By the 0-1 principle a comparator network sorts every input if it sorts
+// every input of zeros and ones.
+func TestSchedulesSortAllZeroOneInputs(t *testing.T) {
+	for _, spec := range []string{"batcher", "bitonic", "oddeven"} {
+		for n := 1; n <= 10; n++ {
+			s, err := ParseSchedule(spec, n)
+			if err != nil {
+				t.Fatalf("ParseSchedule(%q, %d): %v", spec, n, err)
+			}
+			values := make([]int, n)
+			for mask := 0; mask < 1<<n; mask++ {
+				for i := range values {
+					values[i] = mask >> i & 1
+				}
+				apply(s, values)
+				for i := 1; i < n; i++ {
+					if values[i-1] > values[i] {
+						t.Fatalf("%s with %d nodes leaves input %0*b unsorted: %v", spec, n, n, mask, values)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestParseScheduleIgnoresCase(t *testing.T) {
+	for spec, name := range map[string]string{"Batcher": "batcher", "BITONIC": "bitonic", "OddEven": "oddeven"} {
+		s, err := ParseSchedule(spec, 8)
+		if err != nil {
+			t.Fatalf("ParseSchedule(%q): %v", spec, err)
+		}
+		if s.Name != name {
+			t.Errorf("ParseSchedule(%q) built %s, expected %s", spec, s.Name, name)
+		}
+	}
+}
+
+func TestParseScheduleFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "network.txt")
+	if err := os.WriteFile(path, []byte("# two rounds\n0,1 2,3\n1,2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseSchedule("file:"+path, 4); err != nil {
+		t.Errorf("Valid schedule file rejected: %v", err)
+	}
+	if _, err := ParseSchedule("file:"+path, 3); err == nil {
+		t.Error("Expected an error for a comparator beyond the node count")
+	}
+	if _, err := ParseSchedule("shell", 4); err == nil {
+		t.Error("Expected an error for an unknown network")
+	}
+}
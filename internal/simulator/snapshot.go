@@ -0,0 +1,191 @@
+package simulator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+// Snapshot is the state of every node at the end of one round, in the
+// order the nodes' values form the array (Algorithm.Order). Round is -1 for
+// the initial state. A node that has already finished keeps its last state.
+// Ranks holds each value's rank in the sorted input, equal values sharing
+// one, and Inversions counts the pairs of values out of order in the whole
+// array.
+type Snapshot[V any] struct {
+	Round      int
+	Nodes      []int
+	Values     [][]V
+	States     []string
+	Highlights []Highlight
+	Ranks      [][]int
+	Inversions int
+}
+
+// roundObserver is told about every node at the end of each of its rounds.
+type roundObserver[T any] interface {
+	endRound(n *types.Node[T])
+}
+
+// snapshots collects the cells of the round-by-round matrix. Every node
+// fills in its own row at the end of its round, so the matrix does not
+// depend on how the nodes' rounds interleave in time.
+type snapshots[V, T any] struct {
+	values    func(payload T) []V
+	state     func(n *types.Node[T]) string
+	highlight func(n *types.Node[T]) Highlight
+
+	mu    sync.Mutex
+	cells []map[int]snapshotCell[V]
+}
+
+type snapshotCell[V any] struct {
+	values    []V
+	state     string
+	highlight Highlight
+}
+
+func newSnapshots[V, T any](nodeCount int, algo Algorithm[V, T]) *snapshots[V, T] {
+	s := &snapshots[V, T]{values: algo.Values, state: algo.State, highlight: algo.Highlight, cells: make([]map[int]snapshotCell[V], nodeCount)}
+	for id := range s.cells {
+		s.cells[id] = make(map[int]snapshotCell[V])
+	}
+	return s
+}
+
+// begin records n's initial values; endRound its values and state after
+// its current round.
+func (s *snapshots[V, T]) begin(n *types.Node[T]) {
+	s.record(n.ID, -1, snapshotCell[V]{values: slices.Clone(s.values(n.Value))})
+}
+
+func (s *snapshots[V, T]) endRound(n *types.Node[T]) {
+	cell := snapshotCell[V]{values: slices.Clone(s.values(n.Value))}
+	if s.state != nil {
+		cell.state = s.state(n)
+	}
+	if s.highlight != nil {
+		cell.highlight = s.highlight(n)
+	}
+	s.record(n.ID, n.Round, cell)
+}
+
+func (s *snapshots[V, T]) record(id, round int, cell snapshotCell[V]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cells[id][round] = cell
+}
+
+// matrix assembles the snapshots of rounds -1 to the last round any node
+// finished, with columns in the given node order.
+func (s *snapshots[V, T]) matrix(order []int, compare func(a, b V) int) []Snapshot[V] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	last := -1
+	for _, cells := range s.cells {
+		for round := range cells {
+			last = max(last, round)
+		}
+	}
+
+	var sorted []V
+	for _, id := range order {
+		sorted = append(sorted, s.cells[id][-1].values...)
+	}
+	slices.SortFunc(sorted, compare)
+	rank := func(v V) int {
+		r, _ := slices.BinarySearchFunc(sorted, v, compare)
+		return r
+	}
+
+	current := make([]snapshotCell[V], len(s.cells))
+	var matrix []Snapshot[V]
+	for round := -1; round <= last; round++ {
+		snapshot := Snapshot[V]{Round: round, Nodes: order}
+		var array []V
+		for _, id := range order {
+			if cell, ok := s.cells[id][round]; ok {
+				current[id] = cell
+			}
+			snapshot.Values = append(snapshot.Values, current[id].values)
+			snapshot.States = append(snapshot.States, current[id].state)
+			snapshot.Highlights = append(snapshot.Highlights, current[id].highlight)
+			var ranks []int
+			for _, v := range current[id].values {
+				ranks = append(ranks, rank(v))
+			}
+			snapshot.Ranks = append(snapshot.Ranks, ranks)
+			array = append(array, current[id].values...)
+		}
+		snapshot.Inversions = CountInversions(array, compare)
+		matrix = append(matrix, snapshot)
+	}
+	return matrix
+}
+
+// CountInversions counts the pairs i < j with values[i] > values[j].
+func CountInversions[V any](values []V, compare func(a, b V) int) int {
+	if len(values) < 2 {
+		return 0
+	}
+	mid := len(values) / 2
+	left, right := slices.Clone(values[:mid]), slices.Clone(values[mid:])
+	count := CountInversions(left, compare) + CountInversions(right, compare)
+	slices.SortFunc(left, compare)
+	slices.SortFunc(right, compare)
+	j := 0
+	for _, v := range left {
+		for j < len(right) && compare(right[j], v) < 0 {
+			j++
+		}
+		count += j
+	}
+	return count
+}
+
+func printSnapshots[V any](matrix []Snapshot[V]) {
+	rows := [][]string{{"round"}}
+	for _, id := range matrix[0].Nodes {
+		rows[0] = append(rows[0], fmt.Sprintf("N%d", id))
+	}
+	rows[0] = append(rows[0], "inversions")
+	for _, snapshot := range matrix {
+		row := []string{"init"}
+		if snapshot.Round >= 0 {
+			row[0] = fmt.Sprint(snapshot.Round)
+		}
+		for i, values := range snapshot.Values {
+			cell := fmt.Sprint(values)
+			if len(values) == 1 {
+				cell = fmt.Sprint(values[0])
+			}
+			if snapshot.States[i] != "" {
+				cell += " " + snapshot.States[i]
+			}
+			row = append(row, cell)
+		}
+		rows = append(rows, append(row, fmt.Sprint(snapshot.Inversions)))
+	}
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len([]rune(cell)))
+		}
+	}
+	fmt.Printf("\n--- Round Snapshots ---\n")
+	for _, row := range rows {
+		var line strings.Builder
+		for i, cell := range row {
+			if i > 0 {
+				line.WriteString(" | ")
+			}
+			line.WriteString(cell + strings.Repeat(" ", widths[i]-len([]rune(cell))))
+		}
+		fmt.Println(strings.TrimRight(line.String(), " "))
+	}
+}
@@ -0,0 +1,175 @@
+package simulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+// SpaceTime records the nodes' values after every round and every data
+// message exchanged, and renders them as an SVG space-time diagram: one
+// column per node, one band per round from top to bottom, each node's value
+// written on the line between two rounds, and an arrow for every message
+// from the round its sender was in to the round its receiver read it in.
+// It is meant for small N.
+type SpaceTime[T any] struct {
+	name  string
+	label func(payload T) string
+
+	mu     sync.Mutex
+	values [][]string
+	sends  map[messageKey]int
+	arrows []spaceTimeArrow
+}
+
+type spaceTimeArrow struct {
+	from, to             int
+	sendRound, recvRound int
+}
+
+// NewSpaceTime records the nodes of a run; label formats a node's payload
+// for the diagram.
+func NewSpaceTime[T any](name string, nodeCount int, label func(payload T) string) *SpaceTime[T] {
+	return &SpaceTime[T]{
+		name:   name,
+		label:  label,
+		values: make([][]string, nodeCount),
+		sends:  make(map[messageKey]int),
+	}
+}
+
+// hook routes a neighbor's link through the diagram.
+func (s *SpaceTime[T]) hook(n *types.Node[T], nb *types.Neighbor[T]) {
+	nb.Conn = &spaceTimeConn[T]{Conn: nb.Conn, diagram: s, node: n}
+	nb.Buffer = &spaceTimeReceiver[T]{StepReceiver: nb.Buffer, diagram: s, node: n}
+}
+
+// beginSpaceTime records n's initial value, from which on its messages are
+// drawn. Call it right before the algorithm runs.
+func beginSpaceTime[T any](s *SpaceTime[T], n *types.Node[T]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[n.ID] = []string{s.label(n.Value)}
+}
+
+// endRound records n's value at the end of its current round.
+func (s *SpaceTime[T]) endRound(n *types.Node[T]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[n.ID] = append(s.values[n.ID], s.label(n.Value))
+}
+
+// sent and received skip nodes that have not begun, whose messages belong
+// to discovery.
+func (s *SpaceTime[T]) sent(key messageKey, round int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values[key.sender] != nil {
+		s.sends[key] = round
+	}
+}
+
+func (s *SpaceTime[T]) received(key messageKey, round int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sendRound, ok := s.sends[key]; ok && s.values[key.receiver] != nil {
+		delete(s.sends, key)
+		s.arrows = append(s.arrows, spaceTimeArrow{from: key.sender, to: key.receiver, sendRound: sendRound, recvRound: round})
+	}
+}
+
+const (
+	spaceTimeLeft   = 80
+	spaceTimeTop    = 60
+	spaceTimeColumn = 100
+	spaceTimeRow    = 56
+	spaceTimeLabel  = 14
+)
+
+// WriteFile renders the diagram as SVG.
+func (s *SpaceTime[T]) WriteFile(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rounds := 0
+	for _, values := range s.values {
+		rounds = max(rounds, len(values)-1)
+	}
+	x := func(id int) int { return spaceTimeLeft + id*spaceTimeColumn + spaceTimeColumn/2 }
+	y := func(round int) int { return spaceTimeTop + round*spaceTimeRow }
+	width := spaceTimeLeft + len(s.values)*spaceTimeColumn + 20
+	height := y(rounds) + 30
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="monospace" font-size="11">`+"\n", width, height)
+	b.WriteString(`<defs><marker id="head" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="6" markerHeight="6" orient="auto"><path d="M0,0 L10,5 L0,10 z" fill="steelblue"/></marker></defs>` + "\n")
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="white"/>`+"\n", width, height)
+	fmt.Fprintf(&b, `<text x="10" y="20" font-size="14" font-weight="bold">%s</text>`+"\n", html.EscapeString(s.name))
+
+	for r := 0; r <= rounds; r++ {
+		fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#ccc" stroke-dasharray="4 3"/>`+"\n", spaceTimeLeft, y(r), width-20, y(r))
+		if r < rounds {
+			fmt.Fprintf(&b, `<text x="10" y="%d" fill="#666">round %d</text>`+"\n", y(r)+spaceTimeRow/2+4, r)
+		}
+	}
+	for id := range s.values {
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle" font-weight="bold">Node %d</text>`+"\n", x(id), spaceTimeTop-20, id)
+		fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#333"/>`+"\n", x(id), y(0), x(id), y(rounds))
+	}
+
+	// Sends leave in the first third of the sender's round band and arrive
+	// in the last third of the band the receiver read them in.
+	for _, a := range s.arrows {
+		fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="steelblue" marker-end="url(#head)"/>`+"\n",
+			x(a.from), y(a.sendRound)+spaceTimeRow/3, x(a.to), y(a.recvRound)+2*spaceTimeRow/3)
+	}
+
+	for id, values := range s.values {
+		for r, value := range values {
+			if runes := []rune(value); len(runes) > spaceTimeLabel {
+				value = string(runes[:spaceTimeLabel-1]) + "…"
+			}
+			w := 7*len([]rune(value)) + 6
+			fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="14" rx="3" fill="#fff8dc" stroke="#d4b106"/>`+"\n", x(id)-w/2, y(r)-7, w)
+			fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle">%s</text>`+"\n", x(id), y(r)+4, html.EscapeString(value))
+		}
+	}
+	b.WriteString("</svg>\n")
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
+
+type spaceTimeConn[T any] struct {
+	net.Conn
+	diagram *SpaceTime[T]
+	node    *types.Node[T]
+}
+
+func (c *spaceTimeConn[T]) Write(p []byte) (int, error) {
+	var head messageHead
+	if json.Unmarshal(p, &head) == nil && head.Type == types.MsgData {
+		c.diagram.sent(messageKey{sender: c.node.ID, receiver: head.ReceiverID, round: head.Round}, c.node.Round)
+	}
+	return c.Conn.Write(p)
+}
+
+func (c *spaceTimeConn[T]) Unwrap() net.Conn {
+	return c.Conn
+}
+
+type spaceTimeReceiver[T any] struct {
+	types.StepReceiver[T]
+	diagram *SpaceTime[T]
+	node    *types.Node[T]
+}
+
+func (r *spaceTimeReceiver[T]) GetStepMessage(round int) types.Message[T] {
+	msg := r.StepReceiver.GetStepMessage(round)
+	r.diagram.received(messageKey{sender: msg.SenderID, receiver: r.node.ID, round: msg.Round}, r.node.Round)
+	return msg
+}
@@ -0,0 +1,152 @@
+package simulator
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SpeedProfile gives the compute time a node spends on its step in a round,
+// on top of whatever the algorithm itself costs.
+type SpeedProfile interface {
+	Delay(nodeID, round int) time.Duration
+	String() string
+}
+
+type slowNodes struct {
+	label string
+	ids   map[int]bool
+	delay time.Duration
+}
+
+func (s slowNodes) Delay(nodeID, round int) time.Duration {
+	if s.ids[nodeID] {
+		return s.delay
+	}
+	return 0
+}
+
+func (s slowNodes) String() string {
+	ids := make([]int, 0, len(s.ids))
+	for id := range s.ids {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return fmt.Sprintf("%s(%v@%v)", s.label, ids, s.delay)
+}
+
+type jitter struct {
+	max time.Duration
+}
+
+func (j jitter) Delay(nodeID, round int) time.Duration {
+	if j.max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(j.max)))
+}
+
+func (j jitter) String() string {
+	return fmt.Sprintf("jitter(%v)", j.max)
+}
+
+type uniformSpeed struct {
+	delay time.Duration
+}
+
+func (u uniformSpeed) Delay(nodeID, round int) time.Duration {
+	return u.delay
+}
+
+func (u uniformSpeed) String() string {
+	return fmt.Sprintf("uniform(%v)", u.delay)
+}
+
+type combinedSpeed []SpeedProfile
+
+func (c combinedSpeed) Delay(nodeID, round int) time.Duration {
+	var total time.Duration
+	for _, p := range c {
+		total += p.Delay(nodeID, round)
+	}
+	return total
+}
+
+func (c combinedSpeed) String() string {
+	parts := make([]string, len(c))
+	for i, p := range c {
+		parts[i] = p.String()
+	}
+	return strings.Join(parts, "+")
+}
+
+// ParseSpeedProfile reads a node speed spec made of one or more profiles
+// joined with '+':
+//
+//	uniform:DELAY        every node spends DELAY per round
+//	slow:ID,ID,...@DELAY the listed nodes spend DELAY per round
+//	stragglers:K@DELAY   K randomly chosen nodes spend DELAY per round
+//	jitter:MAX           every node spends a random [0, MAX) per round
+func ParseSpeedProfile(spec string, nodeCount int) (SpeedProfile, error) {
+	if spec == "" || strings.EqualFold(spec, "none") {
+		return nil, nil
+	}
+
+	var profiles combinedSpeed
+	for _, part := range strings.Split(spec, "+") {
+		kind, args, _ := strings.Cut(strings.TrimSpace(part), ":")
+		kind = strings.ToLower(kind)
+
+		switch kind {
+		case "uniform", "jitter":
+			d, err := time.ParseDuration(args)
+			if err != nil {
+				return nil, fmt.Errorf("node speed %q: %w", part, err)
+			}
+			if kind == "uniform" {
+				profiles = append(profiles, uniformSpeed{delay: d})
+			} else {
+				profiles = append(profiles, jitter{max: d})
+			}
+		case "slow", "stragglers":
+			who, delayStr, ok := strings.Cut(args, "@")
+			if !ok {
+				return nil, fmt.Errorf("node speed %q: expected %s:...@DELAY", part, kind)
+			}
+			d, err := time.ParseDuration(delayStr)
+			if err != nil {
+				return nil, fmt.Errorf("node speed %q: %w", part, err)
+			}
+
+			ids := make(map[int]bool)
+			if kind == "slow" {
+				for _, s := range strings.Split(who, ",") {
+					id, err := strconv.Atoi(strings.TrimSpace(s))
+					if err != nil || id < 0 || id >= nodeCount {
+						return nil, fmt.Errorf("node speed %q: invalid node id %q", part, s)
+					}
+					ids[id] = true
+				}
+			} else {
+				k, err := strconv.Atoi(who)
+				if err != nil || k < 0 || k > nodeCount {
+					return nil, fmt.Errorf("node speed %q: invalid straggler count %q", part, who)
+				}
+				for _, id := range rand.Perm(nodeCount)[:k] {
+					ids[id] = true
+				}
+			}
+			profiles = append(profiles, slowNodes{label: kind, ids: ids, delay: d})
+		default:
+			return nil, fmt.Errorf("unknown node speed profile %q", kind)
+		}
+	}
+
+	if len(profiles) == 1 {
+		return profiles[0], nil
+	}
+	return profiles, nil
+}
@@ -0,0 +1,98 @@
+package simulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+func TestParseSpeedProfile(t *testing.T) {
+	tests := []struct {
+		spec  string
+		node  int
+		delay time.Duration
+	}{
+		{"uniform:10ms", 3, 10 * time.Millisecond},
+		{"UNIFORM:10ms", 3, 10 * time.Millisecond},
+		{"Slow:1,2@5ms", 2, 5 * time.Millisecond},
+		{"slow:1,2@5ms", 0, 0},
+		{"uniform:1ms+SLOW:0@4ms", 0, 5 * time.Millisecond},
+		{"uniform:1ms + slow:0@4ms", 1, time.Millisecond},
+		{"Stragglers:4@2ms", 3, 2 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		profile, err := ParseSpeedProfile(tt.spec, 4)
+		if err != nil {
+			t.Fatalf("ParseSpeedProfile(%q): %v", tt.spec, err)
+		}
+		if got := profile.Delay(tt.node, 0); got != tt.delay {
+			t.Errorf("ParseSpeedProfile(%q): node %d delay %v, expected %v", tt.spec, tt.node, got, tt.delay)
+		}
+	}
+
+	for _, spec := range []string{"", "none", "NONE"} {
+		if profile, err := ParseSpeedProfile(spec, 4); err != nil || profile != nil {
+			t.Errorf("ParseSpeedProfile(%q) = %v, %v, expected no profile", spec, profile, err)
+		}
+	}
+}
+
+func TestParseSpeedProfileErrors(t *testing.T) {
+	for _, spec := range []string{
+		"uniform:fast",
+		"slow:1@",
+		"slow:4@1ms",
+		"slow:1",
+		"stragglers:5@1ms",
+		"turbo:1ms",
+		"uniform:1ms+",
+	} {
+		if _, err := ParseSpeedProfile(spec, 4); err == nil {
+			t.Errorf("ParseSpeedProfile(%q): expected an error", spec)
+		}
+	}
+}
+
+func TestJitterStaysBelowMax(t *testing.T) {
+	profile, err := ParseSpeedProfile("Jitter:2ms", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for round := range 100 {
+		if d := profile.Delay(0, round); d < 0 || d >= 2*time.Millisecond {
+			t.Fatalf("Jitter delay %v outside [0, 2ms)", d)
+		}
+	}
+}
+
+// roundOne is slow only in round 1.
+type roundOne struct{}
+
+func (roundOne) Delay(nodeID, round int) time.Duration {
+	if round == 1 {
+		return 50 * time.Millisecond
+	}
+	return 0
+}
+
+func (roundOne) String() string { return "round-one" }
+
+func TestDelayPrecedesRound(t *testing.T) {
+	engine := NewEngine[int](1)
+	engine.Speed = roundOne{}
+	node := &types.Node[int]{}
+
+	// Ending round 0 enters round 1, whose compute time comes before any
+	// of its sends.
+	start := time.Now()
+	engine.IncrementClock(node)
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Entering round 1 took %v, expected its 50ms compute time", elapsed)
+	}
+	start = time.Now()
+	engine.IncrementClock(node)
+	if elapsed := time.Since(start); elapsed >= 50*time.Millisecond {
+		t.Errorf("Entering round 2 took %v, expected no compute time", elapsed)
+	}
+}
@@ -0,0 +1,169 @@
+package simulator
+
+import (
+	"math/bits"
+	"sync"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/transport"
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+// DefaultTerminationLag is how many rounds an algorithm keeps running while
+// the decision for a round travels up and down the spanning tree.
+const DefaultTerminationLag = 4
+
+// TerminationDetector decides round by round whether every node is stable,
+// using nothing but MsgTerm messages, so it works wherever the links do.
+// Votes travel up a spanning tree of the topology: a node ANDs its own vote
+// with its children's and passes the result to its parent. The root's
+// result travels back down, and every node records the same decision.
+//
+// The protocol runs in its own goroutine: Vote never blocks, and an
+// algorithm that checks Terminated(round - Lag) after voting for round gives
+// the decision Lag rounds to arrive. Since every node checks the same round,
+// all of them stop after the same round.
+type TerminationDetector[T any] struct {
+	Lag int
+
+	id       int
+	node     *types.Node[T]
+	parent   *types.Neighbor[T]
+	children []*types.Neighbor[T]
+	votes    chan terminationVote
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	decisions []bool
+}
+
+type terminationVote struct {
+	round  int
+	stable bool
+}
+
+// NewTerminationDetector starts the detector for node n on the given
+// topology. Votes must be cast for rounds 0, 1, 2, ... in order.
+func NewTerminationDetector[T any](n *types.Node[T], topology types.Topology) *TerminationDetector[T] {
+	d := &TerminationDetector[T]{
+		Lag:   DefaultTerminationLag,
+		id:    n.ID,
+		node:  n,
+		votes: make(chan terminationVote, 64),
+	}
+	d.cond = sync.NewCond(&d.mu)
+	d.parent, d.children = spanningTree(n, topology)
+	go d.run()
+	return d
+}
+
+// Vote records whether the node is stable after round.
+func (d *TerminationDetector[T]) Vote(round int, stable bool) {
+	d.votes <- terminationVote{round: round, stable: stable}
+}
+
+// Terminated waits for the decision on round and reports whether every node
+// voted stable in it. Rounds before 0 are never terminated. A node about to
+// wait first writes out what its links hold back, as the decision may need
+// the messages to reach its neighbors.
+func (d *TerminationDetector[T]) Terminated(round int) bool {
+	if round < 0 {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.decisions) <= round {
+		d.mu.Unlock()
+		FlushLinks(d.node)
+		d.mu.Lock()
+	}
+	for len(d.decisions) <= round {
+		d.cond.Wait()
+	}
+	return d.decisions[round]
+}
+
+// Close stops the detector once the votes cast so far are decided.
+func (d *TerminationDetector[T]) Close() {
+	close(d.votes)
+}
+
+func (d *TerminationDetector[T]) run() {
+	send := func(nb *types.Neighbor[T], round int, stable bool) {
+		msg := types.Message[T]{Type: types.MsgTerm, SenderID: d.id, ReceiverID: nb.ID, Round: round}
+		if stable {
+			msg.Sequence = 1
+		}
+		_ = transport.SendMessage(nb.Conn, msg)
+	}
+
+	for v := range d.votes {
+		stable := v.stable
+		for _, child := range d.children {
+			stable = child.Control.GetStepMessage(v.round).Sequence == 1 && stable
+		}
+		if d.parent != nil {
+			send(d.parent, v.round, stable)
+			stable = d.parent.Control.GetStepMessage(v.round).Sequence == 1
+		}
+		for _, child := range d.children {
+			send(child, v.round, stable)
+			transport.Flush(child.Conn)
+		}
+
+		d.mu.Lock()
+		d.decisions = append(d.decisions, stable)
+		d.cond.Broadcast()
+		d.mu.Unlock()
+	}
+}
+
+// spanningTree picks a parent and children for n so that the links form a
+// tree rooted at node 0: the line itself (dropping a ring's wrap link), the
+// first column plus every row on a mesh, the binomial tree on a hypercube
+// (the parent clears the lowest set bit of the ID), the tree itself, and a
+// star around node 0 on a complete network.
+func spanningTree[T any](n *types.Node[T], topology types.Topology) (parent *types.Neighbor[T], children []*types.Neighbor[T]) {
+	addChild := func(nb *types.Neighbor[T]) {
+		if nb != nil {
+			children = append(children, nb)
+		}
+	}
+
+	switch topology {
+	case types.Mesh:
+		parent = n.Neighbor(types.Left)
+		if parent == nil {
+			parent = n.Neighbor(types.Up)
+			addChild(n.Neighbor(types.Down))
+		}
+		addChild(n.Neighbor(types.Right))
+	case types.Hypercube:
+		low := bits.TrailingZeros(uint(n.ID))
+		if n.ID != 0 {
+			parent = n.Neighbor(types.Dimension(low))
+		}
+		for dim := 0; dim < min(low, len(n.Neighbors)); dim++ {
+			addChild(n.Neighbor(types.Dimension(dim)))
+		}
+	case types.Tree:
+		parent = n.Neighbor(types.Parent)
+		addChild(n.Neighbor(types.Child(0)))
+		addChild(n.Neighbor(types.Child(1)))
+	case types.Complete:
+		if n.ID != 0 {
+			parent = n.Neighbor(types.Peer(0))
+		} else {
+			for id := 1; id < n.TotalNode; id++ {
+				addChild(n.Neighbor(types.Peer(id)))
+			}
+		}
+	default:
+		if n.ID > 0 {
+			parent = n.Neighbor(types.Left)
+		}
+		if n.ID < n.TotalNode-1 {
+			addChild(n.Neighbor(types.Right))
+		}
+	}
+	return parent, children
+}
@@ -0,0 +1,220 @@
+package simulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+// Timeline collects what every node spends its time on and writes it in the
+// Chrome Trace Event format, which chrome://tracing and Perfetto open. Each
+// node is a track holding its round spans, the time it waits for neighbor
+// messages inside them, and a flow arrow from every data message's send to
+// the moment the receiver reads it.
+type Timeline struct {
+	name  string
+	start time.Time
+
+	mu     sync.Mutex
+	events []chromeEvent
+	nodes  []timelineNode
+}
+
+type timelineNode struct {
+	// running is set once the node's rounds begin; what it sends and waits
+	// for before that is discovery, which the timeline leaves out.
+	running    bool
+	roundStart time.Time
+
+	// Waits on several links at once (WaitForNeighbors) are merged into one
+	// span, so the spans on a track always nest.
+	waiting   int
+	waitStart time.Time
+	waitRound int
+	waitLinks []string
+}
+
+type chromeEvent struct {
+	Name string         `json:"name"`
+	Cat  string         `json:"cat,omitempty"`
+	Ph   string         `json:"ph"`
+	Ts   float64        `json:"ts"`
+	Dur  float64        `json:"dur,omitempty"`
+	Pid  int            `json:"pid"`
+	Tid  int            `json:"tid"`
+	ID   string         `json:"id,omitempty"`
+	BP   string         `json:"bp,omitempty"`
+	Args map[string]any `json:"args,omitempty"`
+}
+
+func NewTimeline(name string, nodeCount int) *Timeline {
+	t := &Timeline{name: name, start: time.Now(), nodes: make([]timelineNode, nodeCount)}
+	t.events = append(t.events, chromeEvent{Name: "process_name", Ph: "M", Args: map[string]any{"name": name}})
+	for id := range nodeCount {
+		t.events = append(t.events,
+			chromeEvent{Name: "thread_name", Ph: "M", Tid: id, Args: map[string]any{"name": fmt.Sprintf("Node %d", id)}},
+			chromeEvent{Name: "thread_sort_index", Ph: "M", Tid: id, Args: map[string]any{"sort_index": id}},
+		)
+	}
+	return t
+}
+
+// micros is the trace timestamp of at.
+func (t *Timeline) micros(at time.Time) float64 {
+	return float64(at.Sub(t.start).Nanoseconds()) / 1e3
+}
+
+// timelineHook routes a neighbor's link through the timeline.
+func timelineHook[T any](t *Timeline) NeighborHook[T] {
+	return func(n *types.Node[T], nb *types.Neighbor[T]) {
+		nb.Conn = &timelineConn{Conn: nb.Conn, timeline: t, node: n.ID}
+		nb.Buffer = &timelineReceiver[T]{StepReceiver: nb.Buffer, timeline: t, node: n.ID, label: nb.Label}
+	}
+}
+
+// beginRounds marks the start of n's first round, from which on its links
+// are drawn. Call it right before the algorithm runs.
+func beginRounds[T any](t *Timeline, n *types.Node[T]) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nodes[n.ID].running = true
+	t.nodes[n.ID].roundStart = time.Now()
+}
+
+// endRound closes the span of the node's current round.
+func (t *Timeline) endRound(id, round int) {
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	node := &t.nodes[id]
+	t.events = append(t.events, chromeEvent{
+		Name: fmt.Sprintf("round %d", round),
+		Cat:  "round",
+		Ph:   "X",
+		Ts:   t.micros(node.roundStart),
+		Dur:  t.micros(now) - t.micros(node.roundStart),
+		Tid:  id,
+		Args: map[string]any{"round": round},
+	})
+	node.roundStart = now
+}
+
+func (t *Timeline) beginWait(id, round int, label types.Direction) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	node := &t.nodes[id]
+	if !node.running {
+		return
+	}
+	if node.waiting == 0 {
+		node.waitStart, node.waitRound, node.waitLinks = time.Now(), round, nil
+	}
+	node.waiting++
+	node.waitLinks = append(node.waitLinks, string(label))
+}
+
+// endWait ends one wait and, if it was the last, the node's wait span; the
+// message read is the end of a flow arrow from its sender.
+func (t *Timeline) endWait(id int, msg messageKey) {
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	node := &t.nodes[id]
+	if !node.running {
+		return
+	}
+	node.waiting--
+	if node.waiting == 0 {
+		t.events = append(t.events, chromeEvent{
+			Name: "wait " + strings.Join(node.waitLinks, "+"),
+			Cat:  "wait",
+			Ph:   "X",
+			Ts:   t.micros(node.waitStart),
+			Dur:  t.micros(now) - t.micros(node.waitStart),
+			Tid:  id,
+			Args: map[string]any{"round": node.waitRound},
+		})
+	}
+	t.events = append(t.events, chromeEvent{Name: "message", Cat: "message", Ph: "f", BP: "e", Ts: t.micros(now), Tid: id, ID: msg.String()})
+}
+
+// sent starts the flow arrow of a data message on the sender's track.
+func (t *Timeline) sent(id int, msg messageKey) {
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.nodes[id].running {
+		return
+	}
+	t.events = append(t.events, chromeEvent{
+		Name: "message",
+		Cat:  "message",
+		Ph:   "s",
+		Ts:   t.micros(now),
+		Tid:  id,
+		ID:   msg.String(),
+		Args: map[string]any{"to": msg.receiver, "round": msg.round},
+	})
+}
+
+// WriteFile writes the timeline as a Chrome Trace Event JSON object.
+func (t *Timeline) WriteFile(path string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	data, err := json.Marshal(struct {
+		TraceEvents     []chromeEvent `json:"traceEvents"`
+		DisplayTimeUnit string        `json:"displayTimeUnit"`
+	}{t.events, "ms"})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// messageKey identifies a data message on both ends of its link; a link
+// carries one data message per round.
+type messageKey struct {
+	sender, receiver, round int
+}
+
+func (k messageKey) String() string {
+	return fmt.Sprintf("%d->%d@%d", k.sender, k.receiver, k.round)
+}
+
+type timelineConn struct {
+	net.Conn
+	timeline *Timeline
+	node     int
+}
+
+func (c *timelineConn) Write(p []byte) (int, error) {
+	var head messageHead
+	if json.Unmarshal(p, &head) == nil && head.Type == types.MsgData {
+		c.timeline.sent(c.node, messageKey{sender: c.node, receiver: head.ReceiverID, round: head.Round})
+	}
+	return c.Conn.Write(p)
+}
+
+func (c *timelineConn) Unwrap() net.Conn {
+	return c.Conn
+}
+
+type timelineReceiver[T any] struct {
+	types.StepReceiver[T]
+	timeline *Timeline
+	node     int
+	label    types.Direction
+}
+
+func (r *timelineReceiver[T]) GetStepMessage(round int) types.Message[T] {
+	r.timeline.beginWait(r.node, round, r.label)
+	msg := r.StepReceiver.GetStepMessage(round)
+	r.timeline.endWait(r.node, messageKey{sender: msg.SenderID, receiver: r.node, round: msg.Round})
+	return msg
+}
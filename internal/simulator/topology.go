@@ -0,0 +1,79 @@
+package simulator
+
+import (
+	"math/bits"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+// neighborSpec is one link SetupNode has to establish. Exactly one side of
+// every link dials; the other accepts.
+type neighborSpec struct {
+	label  types.Direction
+	peerID int
+	dial   bool
+}
+
+// neighborSpecs lists the links of node n: on a line ID-1 and ID+1, on a
+// ring additionally from node N-1 back to node 0, on a mesh of config.Rows
+// rows the four grid neighbors, on a hypercube the log2(N) nodes whose IDs
+// differ in one bit, and on a binary tree in heap layout the parent (ID-1)/2
+// and children 2*ID+1, 2*ID+2, and in a complete network every other node.
+// Line, ring and mesh nodes accept from the left and above and dial right
+// and down; hypercube and complete nodes dial the higher ID; tree nodes dial
+// their parent.
+func neighborSpecs[T any](n *types.Node[T], config types.Config) []neighborSpec {
+	var specs []neighborSpec
+	add := func(label types.Direction, peerID int, dial bool) {
+		specs = append(specs, neighborSpec{label: label, peerID: peerID, dial: dial})
+	}
+
+	switch config.Topology {
+	case types.Ring:
+		add(types.Left, (n.ID-1+n.TotalNode)%n.TotalNode, false)
+		add(types.Right, (n.ID+1)%n.TotalNode, true)
+	case types.Mesh:
+		cols := n.TotalNode / int(config.Rows)
+		row, col := n.ID/cols, n.ID%cols
+		if col > 0 {
+			add(types.Left, n.ID-1, false)
+		}
+		if row > 0 {
+			add(types.Up, n.ID-cols, false)
+		}
+		if col < cols-1 {
+			add(types.Right, n.ID+1, true)
+		}
+		if row < int(config.Rows)-1 {
+			add(types.Down, n.ID+cols, true)
+		}
+	case types.Hypercube:
+		for d := 0; d < bits.Len(uint(n.TotalNode))-1; d++ {
+			peerID := n.ID ^ (1 << d)
+			add(types.Dimension(d), peerID, peerID > n.ID)
+		}
+	case types.Tree:
+		if n.ID > 0 {
+			add(types.Parent, (n.ID-1)/2, true)
+		}
+		for i := range 2 {
+			if child := 2*n.ID + 1 + i; child < n.TotalNode {
+				add(types.Child(i), child, false)
+			}
+		}
+	case types.Complete:
+		for peerID := range n.TotalNode {
+			if peerID != n.ID {
+				add(types.Peer(peerID), peerID, peerID > n.ID)
+			}
+		}
+	default:
+		if n.Position != types.Head {
+			add(types.Left, n.ID-1, false)
+		}
+		if n.Position != types.Tail && n.ID+1 < n.TotalNode {
+			add(types.Right, n.ID+1, true)
+		}
+	}
+	return specs
+}
@@ -0,0 +1,227 @@
+package simulator
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+type TraceKind string
+
+const (
+	// TraceStart holds a node's initial payload and links once discovery
+	// is done, TraceEnd its final payload and round.
+	TraceStart TraceKind = "start"
+	TraceSend  TraceKind = "send"
+	TraceRecv  TraceKind = "recv"
+	TraceEnd   TraceKind = "end"
+)
+
+// TraceHeader is the first line of a trace: everything besides the
+// recorded messages that a replay needs to rebuild the run.
+type TraceHeader struct {
+	Algorithm string       `json:"algorithm"`
+	Config    types.Config `json:"config"`
+	Schedule  Schedule     `json:"schedule,omitzero"`
+	Started   time.Time    `json:"started"`
+}
+
+// TraceEvent is one line of a trace after the header. Seq orders a node's
+// events as the node saw them. Messages are recorded whole, as sent on the
+// wire; a receive is recorded when the node consumes the message, not when
+// it arrives, so replaying receives in Seq order feeds the node exactly
+// what it read.
+type TraceEvent struct {
+	Node    int             `json:"node"`
+	Seq     int             `json:"seq"`
+	Kind    TraceKind       `json:"kind"`
+	Label   types.Direction `json:"label,omitempty"`
+	Peer    int             `json:"peer"`
+	Round   int             `json:"round"`
+	Control bool            `json:"control,omitempty"`
+	Message json.RawMessage `json:"message,omitempty"`
+
+	// Set on TraceStart only.
+	Total     int             `json:"total,omitempty"`
+	Rows      int             `json:"rows,omitempty"`
+	Cols      int             `json:"cols,omitempty"`
+	Position  types.Position  `json:"position,omitempty"`
+	Rank      int             `json:"rank,omitempty"`
+	Neighbors []TraceNeighbor `json:"neighbors,omitempty"`
+}
+
+type TraceNeighbor struct {
+	Label types.Direction `json:"label"`
+	ID    int             `json:"id"`
+}
+
+// TraceRecorder writes every message the nodes send and consume to a trace
+// file, one JSON object per line, for replayTrace.
+type TraceRecorder struct {
+	mu   sync.Mutex
+	file *os.File
+	w    *bufio.Writer
+	enc  *json.Encoder
+	seq  []int
+	err  error
+
+	// started marks the nodes whose TraceStart is recorded; events of a
+	// node before that are dropped.
+	started []bool
+}
+
+func NewTraceRecorder(path string, header TraceHeader, nodeCount int) (*TraceRecorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(file)
+	r := &TraceRecorder{file: file, w: w, enc: json.NewEncoder(w), seq: make([]int, nodeCount), started: make([]bool, nodeCount)}
+	if err := r.enc.Encode(header); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *TraceRecorder) record(event TraceEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if event.Kind == TraceStart {
+		r.started[event.Node] = true
+	} else if !r.started[event.Node] {
+		return
+	}
+	event.Seq = r.seq[event.Node]
+	r.seq[event.Node]++
+	if err := r.enc.Encode(event); err != nil && r.err == nil {
+		r.err = err
+	}
+}
+
+// traceHook routes a neighbor's link through the recorder. Nothing is
+// recorded for a node before startTrace, so discovery stays out of the trace.
+func traceHook[T any](r *TraceRecorder) NeighborHook[T] {
+	return func(n *types.Node[T], nb *types.Neighbor[T]) {
+		nb.Conn = &tracedConn{Conn: nb.Conn, recorder: r, node: n.ID, label: nb.Label, peer: nb.ID}
+		nb.Buffer = &tracedReceiver[T]{StepReceiver: nb.Buffer, recorder: r, node: n.ID, label: nb.Label, peer: nb.ID}
+		nb.Control = &tracedReceiver[T]{StepReceiver: nb.Control, recorder: r, node: n.ID, label: nb.Label, peer: nb.ID, control: true}
+	}
+}
+
+// startTrace records n's initial state, after which its links are recorded.
+// Call it after discovery, once n.Value holds the initial block.
+func startTrace[T any](r *TraceRecorder, n *types.Node[T], engine *SimulatorEngine[T]) {
+	event := TraceEvent{
+		Node:     n.ID,
+		Kind:     TraceStart,
+		Total:    n.TotalNode,
+		Rows:     engine.Rows,
+		Cols:     engine.Cols,
+		Position: n.Position,
+		Rank:     n.Rank,
+		Message:  mustMarshal(n.Value),
+	}
+	for label, nb := range n.Neighbors {
+		event.Neighbors = append(event.Neighbors, TraceNeighbor{Label: label, ID: nb.ID})
+	}
+	r.record(event)
+}
+
+// endTrace records n's final state.
+func endTrace[T any](r *TraceRecorder, n *types.Node[T]) {
+	r.record(TraceEvent{Node: n.ID, Kind: TraceEnd, Round: n.Round, Message: mustMarshal(n.Value)})
+}
+
+// Close flushes the trace and returns the first error writing it.
+func (r *TraceRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.w.Flush(); err != nil && r.err == nil {
+		r.err = err
+	}
+	if err := r.file.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
+
+// tracedConn records every message written to the link. transport.
+// SendMessage encodes a message with a single Write, so each Write is one
+// message.
+type tracedConn struct {
+	net.Conn
+	recorder *TraceRecorder
+	node     int
+	label    types.Direction
+	peer     int
+}
+
+func (c *tracedConn) Write(p []byte) (int, error) {
+	var head messageHead
+	if err := json.Unmarshal(p, &head); err != nil {
+		return 0, fmt.Errorf("trace node %d: write on %s is not a message: %w", c.node, c.label, err)
+	}
+	c.recorder.record(TraceEvent{
+		Node:    c.node,
+		Kind:    TraceSend,
+		Label:   c.label,
+		Peer:    c.peer,
+		Round:   head.Round,
+		Control: head.Type == types.MsgTerm,
+		Message: json.RawMessage(bytes.TrimSpace(p)),
+	})
+	return c.Conn.Write(p)
+}
+
+func (c *tracedConn) Unwrap() net.Conn {
+	return c.Conn
+}
+
+type tracedReceiver[T any] struct {
+	types.StepReceiver[T]
+	recorder *TraceRecorder
+	node     int
+	label    types.Direction
+	peer     int
+	control  bool
+}
+
+func (t *tracedReceiver[T]) GetStepMessage(round int) types.Message[T] {
+	msg := t.StepReceiver.GetStepMessage(round)
+	t.recorder.record(TraceEvent{
+		Node:    t.node,
+		Kind:    TraceRecv,
+		Label:   t.label,
+		Peer:    t.peer,
+		Round:   round,
+		Control: t.control,
+		Message: mustMarshal(msg),
+	})
+	return msg
+}
+
+// messageHead is the part of an encoded types.Message that does not depend
+// on the payload type.
+type messageHead struct {
+	Round      int               `json:"round"`
+	Type       types.MessageType `json:"type"`
+	ReceiverID int               `json:"receiver_id"`
+	Sequence   uint64            `json:"sequence"`
+	Body       json.RawMessage   `json:"body"`
+}
+
+func mustMarshal(v any) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("trace: %v", err))
+	}
+	return data
+}
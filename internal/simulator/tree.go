@@ -0,0 +1,43 @@
+package simulator
+
+import (
+	"time"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/internal/transport"
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+// DiscoverTree counts the nodes of a binary tree: subtree sizes are
+// convergecast from the leaves to the root, which broadcasts the total back
+// down. Both waves use round 0, one message per link in each direction.
+func DiscoverTree[T any](n *types.Node[T]) (int, error) {
+	time.Sleep(200 * time.Millisecond)
+
+	size := 1
+	for i := range 2 {
+		if child := n.Neighbor(types.Child(i)); child != nil {
+			size += int(child.Buffer.GetStepMessage(0).Sequence)
+		}
+	}
+
+	total := size
+	if parent := n.Neighbor(types.Parent); parent != nil {
+		msg := types.Message[T]{Type: types.MsgCount, SenderID: n.ID, ReceiverID: parent.ID, Round: 0, Sequence: uint64(size)}
+		if err := transport.SendMessage(parent.Conn, msg); err != nil {
+			return -1, err
+		}
+		total = int(parent.Buffer.GetStepMessage(0).Sequence)
+	}
+
+	for i := range 2 {
+		if child := n.Neighbor(types.Child(i)); child != nil {
+			msg := types.Message[T]{Type: types.MsgInit, SenderID: n.ID, ReceiverID: child.ID, Round: 0, Sequence: uint64(total)}
+			if err := transport.SendMessage(child.Conn, msg); err != nil {
+				return -1, err
+			}
+		}
+	}
+
+	n.TotalNode = total
+	return total, nil
+}
@@ -0,0 +1,101 @@
+package simulator
+
+import (
+	"cmp"
+	"fmt"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+// ValueKind maps the generated integer inputs onto an element type and
+// supplies the comparator the algorithms sort with. FromInt receives the
+// value's position in the initial array and must preserve order so that
+// sorted and reverse inputs stay sorted and reverse. Kinds whose elements
+// remember their original position set Index, which enables the stability
+// check.
+type ValueKind[V any] struct {
+	Name    string
+	FromInt func(index, v int) V
+	Compare func(a, b V) int
+	Index   func(v V) int
+}
+
+func (k ValueKind[V]) Block(start int, values []int) []V {
+	block := make([]V, len(values))
+	for i, v := range values {
+		block[i] = k.FromInt(start+i, v)
+	}
+	return block
+}
+
+// Ordered builds a ValueKind for any cmp.Ordered type.
+func Ordered[V cmp.Ordered](name string, fromInt func(v int) V) ValueKind[V] {
+	return ValueKind[V]{
+		Name:    name,
+		FromInt: func(_, v int) V { return fromInt(v) },
+		Compare: cmp.Compare[V],
+	}
+}
+
+var (
+	IntValues    = Ordered("int", func(v int) int { return v })
+	FloatValues  = Ordered("float", func(v int) float64 { return float64(v) / 4 })
+	StringValues = Ordered("string", letters)
+
+	// RecordValues sorts records by key only. Keys are the generated values
+	// divided by recordSpan, and random record inputs draw only RecordKeys
+	// keys, so random, sorted and reverse inputs all contain runs of equal
+	// keys whose relative order exposes unstable algorithms.
+	RecordValues = ValueKind[types.Record]{
+		Name: "record",
+		FromInt: func(index, v int) types.Record {
+			return types.Record{Key: v / recordSpan, Payload: fmt.Sprintf("item-%d", index), Index: index}
+		},
+		Compare: types.CompareRecords,
+		Index:   func(r types.Record) int { return r.Index },
+	}
+)
+
+const (
+	// RecordKeys is the number of distinct keys random record inputs draw
+	// from, few enough that most keys repeat even on short lines.
+	RecordKeys = 8
+	// recordSpan is the number of consecutive values sharing a record key.
+	recordSpan = 8
+)
+
+// randomRange is the range random inputs of k values per node draw from.
+func randomRange(config types.Config, k int) int {
+	if config.ValueType == types.RecordValues {
+		return RecordKeys * recordSpan
+	}
+	return 1000 * k
+}
+
+// letters spells v in fixed-width base 26 so that string order matches
+// integer order.
+func letters(v int) string {
+	const width = 6
+	digits := make([]byte, width)
+	for i := width - 1; i >= 0; i-- {
+		digits[i] = byte('a' + v%26)
+		v /= 26
+	}
+	return string(digits)
+}
+
+// CheckStability reports whether elements with equal keys kept their
+// original relative order. It returns the number of adjacent equal-key pairs
+// found out of order, and the position of the first one (-1 if none).
+func CheckStability[V any](final []V, compare func(a, b V) int, index func(v V) int) (inversions, first int) {
+	first = -1
+	for i := 1; i < len(final); i++ {
+		if compare(final[i-1], final[i]) == 0 && index(final[i-1]) > index(final[i]) {
+			if first < 0 {
+				first = i
+			}
+			inversions++
+		}
+	}
+	return inversions, first
+}
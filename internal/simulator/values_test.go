@@ -0,0 +1,55 @@
+package simulator
+
+import (
+	"testing"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+func TestCheckStability(t *testing.T) {
+	record := func(key, index int) types.Record { return types.Record{Key: key, Index: index} }
+	tests := []struct {
+		name       string
+		final      []types.Record
+		inversions int
+		first      int
+	}{
+		{"empty", nil, 0, -1},
+		{"distinct keys", []types.Record{record(1, 2), record(2, 0), record(3, 1)}, 0, -1},
+		{"equal keys in order", []types.Record{record(1, 0), record(1, 3), record(2, 1)}, 0, -1},
+		{"equal keys swapped", []types.Record{record(0, 2), record(1, 4), record(1, 1), record(1, 0)}, 2, 2},
+	}
+	for _, tt := range tests {
+		inversions, first := CheckStability(tt.final, types.CompareRecords, RecordValues.Index)
+		if inversions != tt.inversions || first != tt.first {
+			t.Errorf("%s: got %d inversions first at %d, expected %d at %d", tt.name, inversions, first, tt.inversions, tt.first)
+		}
+	}
+}
+
+func TestRecordBlocksCarryTheirIndex(t *testing.T) {
+	block := RecordValues.Block(8, []int{16, 17, 40})
+	for i, r := range block {
+		if r.Index != 8+i {
+			t.Errorf("Record %d has index %d, expected %d", i, r.Index, 8+i)
+		}
+	}
+	if types.CompareRecords(block[0], block[1]) != 0 {
+		t.Errorf("Values 16 and 17 should share a key: %v, %v", block[0], block[1])
+	}
+	if types.CompareRecords(block[1], block[2]) >= 0 {
+		t.Errorf("Expected %v before %v", block[1], block[2])
+	}
+}
+
+func TestRandomRecordsRepeatKeys(t *testing.T) {
+	// 18 records over RecordKeys keys must repeat some.
+	config := types.Config{NodeCount: 6, BlockSize: 3, InputType: types.Random, ValueType: types.RecordValues}
+	for id := range 6 {
+		for _, r := range RecordValues.Block(id*3, GenerateInitialBlock(id, config)) {
+			if r.Key < 0 || r.Key >= RecordKeys {
+				t.Fatalf("Key %d outside [0, %d)", r.Key, RecordKeys)
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package simulator
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Highlight tells the visualizer how to draw a node in a round: nodes with
+// the same non-zero Group worked together in it (a compare-exchange pair,
+// a triplet), and Marked flags a node holding a value the algorithm singles
+// out.
+type Highlight struct {
+	Group  int
+	Marked bool
+}
+
+const (
+	visualizeHeight = 16
+
+	ansiClear  = "\x1b[H\x1b[2J"
+	ansiReset  = "\x1b[0m"
+	ansiDim    = "\x1b[2m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+	ansiRed    = "\x1b[31m"
+)
+
+// animate draws every snapshot as a bar chart of the array, one frame per
+// round. A bar's height is its value's rank in the sorted input, so any
+// value type can be drawn. Groups alternate between cyan and yellow along
+// the array, marked nodes are red.
+func animate[V any](name string, matrix []Snapshot[V], delay time.Duration) {
+	maxRank := 1
+	for _, snapshot := range matrix {
+		for _, ranks := range snapshot.Ranks {
+			for _, rank := range ranks {
+				maxRank = max(maxRank, rank)
+			}
+		}
+	}
+	height := min(maxRank+1, visualizeHeight)
+	bar := func(rank int) int { return 1 + rank*(height-1)/maxRank }
+
+	for i, snapshot := range matrix {
+		if i > 0 {
+			time.Sleep(delay)
+		}
+		var b strings.Builder
+		b.WriteString(ansiClear)
+		round := "initial state"
+		if snapshot.Round >= 0 {
+			round = fmt.Sprintf("after round %d", snapshot.Round)
+		}
+		fmt.Fprintf(&b, "%s: %s, %d inversions (frame %d of %d)\n\n", name, round, snapshot.Inversions, i+1, len(matrix))
+
+		colors := make([]string, len(snapshot.Highlights))
+		group, yellow := 0, true
+		for node, h := range snapshot.Highlights {
+			if h.Group > 0 && h.Group != group {
+				yellow = !yellow
+			}
+			group = h.Group
+			switch {
+			case h.Marked:
+				colors[node] = ansiRed
+			case h.Group == 0:
+				colors[node] = ansiDim
+			case yellow:
+				colors[node] = ansiYellow
+			default:
+				colors[node] = ansiCyan
+			}
+		}
+
+		for row := height; row >= 1; row-- {
+			for node, ranks := range snapshot.Ranks {
+				b.WriteString(colors[node])
+				for _, rank := range ranks {
+					if bar(rank) >= row {
+						b.WriteString("██ ")
+					} else {
+						b.WriteString("   ")
+					}
+				}
+				b.WriteString(ansiReset + " ")
+			}
+			b.WriteString("\n")
+		}
+
+		for node, id := range snapshot.Nodes {
+			label := fmt.Sprint(id)
+			width := 3*len(snapshot.Ranks[node]) + 1
+			b.WriteString(label + strings.Repeat(" ", max(width-len(label), 1)))
+		}
+		b.WriteString("\n")
+		fmt.Print(b.String())
+	}
+	fmt.Printf("\n%sgroup%s %sgroup%s %smarked%s\n", ansiCyan, ansiReset, ansiYellow, ansiReset, ansiRed, ansiReset)
+}
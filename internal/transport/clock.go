@@ -0,0 +1,191 @@
+package transport
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+type ClockMode string
+
+const (
+	NoClocks      ClockMode = "none"
+	LamportClocks ClockMode = "lamport"
+	VectorClocks  ClockMode = "vector"
+)
+
+func ParseClockMode(spec string) (ClockMode, error) {
+	switch mode := ClockMode(strings.ToLower(spec)); mode {
+	case "", NoClocks:
+		return NoClocks, nil
+	case LamportClocks, VectorClocks:
+		return mode, nil
+	default:
+		return NoClocks, fmt.Errorf("unknown clock mode %q", spec)
+	}
+}
+
+// Clock is one node's logical clock. The node's links stamp every message
+// it sends with its Lamport clock (and vector clock in VectorClocks mode),
+// and Observe merges the stamps a received message carries into the
+// receiving node's own Clock, so the happened-before relation between any
+// two messages can be read off their stamps. Every data message sent and
+// received is also recorded by round for ValidateClocks.
+type Clock struct {
+	Mode ClockMode
+
+	mu      sync.Mutex
+	id      int
+	lamport uint64
+	vector  []uint64
+
+	// Per round: the first time a data message of the round was sent, and
+	// the last time one was received and from whom.
+	sent     map[int]uint64
+	received map[int]receipt
+	messages int
+}
+
+type receipt struct {
+	at   uint64
+	from int
+}
+
+// NewClock returns the clock of node id in a system of nodeCount nodes, or
+// nil for NoClocks.
+func NewClock(mode ClockMode, id, nodeCount int) *Clock {
+	if mode == NoClocks {
+		return nil
+	}
+	c := &Clock{Mode: mode, id: id, sent: make(map[int]uint64), received: make(map[int]receipt)}
+	if mode == VectorClocks {
+		c.vector = make([]uint64, nodeCount)
+	}
+	return c
+}
+
+// send ticks the clock for a send event and returns the stamps.
+func (c *Clock) send(t types.MessageType, round int) (uint64, []uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lamport++
+	var vector []uint64
+	if c.vector != nil {
+		c.vector[c.id]++
+		vector = slices.Clone(c.vector)
+	}
+	if t == types.MsgData {
+		if _, ok := c.sent[round]; !ok {
+			c.sent[round] = c.lamport
+		}
+	}
+	return c.lamport, vector
+}
+
+// receive merges a message's stamps into the clock.
+func (c *Clock) receive(from int, t types.MessageType, round int, lamport uint64, vector []uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lamport = max(c.lamport, lamport) + 1
+	if c.vector != nil {
+		for i := range min(len(vector), len(c.vector)) {
+			c.vector[i] = max(c.vector[i], vector[i])
+		}
+		c.vector[c.id]++
+	}
+	if t == types.MsgData {
+		c.messages++
+		if r, ok := c.received[round]; !ok || c.lamport > r.at {
+			c.received[round] = receipt{at: c.lamport, from: from}
+		}
+	}
+}
+
+// HappenedBefore reports whether the event stamped a causally precedes the
+// event stamped b.
+func HappenedBefore(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	strictly := false
+	for i := range a {
+		if a[i] > b[i] {
+			return false
+		}
+		strictly = strictly || a[i] < b[i]
+	}
+	return strictly
+}
+
+// ValidateClocks checks, once the nodes are done, that every node received
+// each data message of a round before it sent any data message of a later
+// round, i.e. that no message a node sends can be influenced by a round it
+// has not finished yet. Rounds are compared by the node's own Lamport time,
+// which orders its events.
+func ValidateClocks(clocks []*Clock) (messages int, err error) {
+	violations := 0
+	var first string
+	for _, c := range clocks {
+		c.mu.Lock()
+		messages += c.messages
+
+		rounds := make([]int, 0, len(c.sent)+len(c.received))
+		for r := range c.sent {
+			rounds = append(rounds, r)
+		}
+		for r := range c.received {
+			rounds = append(rounds, r)
+		}
+		slices.Sort(rounds)
+		rounds = slices.Compact(rounds)
+
+		var latest receipt
+		latestRound := -1
+		for _, r := range rounds {
+			if sent, ok := c.sent[r]; ok && sent < latest.at {
+				violations++
+				if first == "" {
+					first = fmt.Sprintf("node %d sent round %d before receiving round %d from node %d", c.id, r, latestRound, latest.from)
+				}
+			}
+			if got, ok := c.received[r]; ok && got.at > latest.at {
+				latest, latestRound = got, r
+			}
+		}
+		c.mu.Unlock()
+	}
+
+	if violations > 0 {
+		return messages, fmt.Errorf("%s (%d violations)", first, violations)
+	}
+	return messages, nil
+}
+
+// Observe merges the clocks carried by a message received over conn into
+// the receiving node's Clock.
+func Observe[Payload any](conn net.Conn, message types.Message[Payload]) {
+	if l := linkOf(conn); l != nil && l.opts.Clock != nil {
+		l.opts.Clock.receive(message.SenderID, message.Type, message.Round, message.Lamport, message.Vector)
+	}
+}
+
+// linkOf returns the Link behind conn, looking through connections that
+// wrap one and expose it with Unwrap, or nil if there is none.
+func linkOf(conn net.Conn) *Link {
+	for {
+		switch c := conn.(type) {
+		case *Link:
+			return c
+		case interface{ Unwrap() net.Conn }:
+			conn = c.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
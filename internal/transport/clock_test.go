@@ -0,0 +1,58 @@
+package transport
+
+import (
+	"testing"
+	"time"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+func TestLinkClocksTrackCausality(t *testing.T) {
+	const acceptorID, dialerID = 66, 67
+	clocks := []*Clock{NewClock(VectorClocks, acceptorID, 68), NewClock(VectorClocks, dialerID, 68)}
+	acceptor, dialer := newLinkPairWith(t, acceptorID, dialerID,
+		Options{NoDelay: true, Clock: clocks[0]},
+		Options{NoDelay: true, Clock: clocks[1]})
+
+	toAcceptor := make(chan types.Message[TestPayload], 4)
+	toDialer := make(chan types.Message[TestPayload], 4)
+	go HandleConnection(acceptor, toAcceptor)
+	go HandleConnection(dialer, toDialer)
+
+	receive := func(conn *Link, inbox chan types.Message[TestPayload]) types.Message[TestPayload] {
+		t.Helper()
+		select {
+		case msg := <-inbox:
+			Observe(conn, msg)
+			return msg
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for message")
+			return types.Message[TestPayload]{}
+		}
+	}
+
+	SendMessage(dialer, types.Message[TestPayload]{SenderID: dialerID, Type: types.MsgData, Round: 0})
+	first := receive(acceptor, toAcceptor)
+	SendMessage(acceptor, types.Message[TestPayload]{SenderID: acceptorID, Type: types.MsgData, Round: 1})
+	second := receive(dialer, toDialer)
+
+	if !HappenedBefore(first.Vector, second.Vector) || HappenedBefore(second.Vector, first.Vector) {
+		t.Errorf("Expected round 0 message to happen before round 1: %v vs %v", first.Vector, second.Vector)
+	}
+	if second.Lamport <= first.Lamport {
+		t.Errorf("Lamport clock did not advance: %d then %d", first.Lamport, second.Lamport)
+	}
+	if _, err := ValidateClocks(clocks); err != nil {
+		t.Fatalf("Consistent exchange reported as violation: %v", err)
+	}
+
+	// The dialer moves on to round 3 before the acceptor's round 2 arrives.
+	SendMessage(dialer, types.Message[TestPayload]{SenderID: dialerID, Type: types.MsgData, Round: 3})
+	receive(acceptor, toAcceptor)
+	SendMessage(acceptor, types.Message[TestPayload]{SenderID: acceptorID, Type: types.MsgData, Round: 2})
+	receive(dialer, toDialer)
+
+	if _, err := ValidateClocks(clocks); err == nil {
+		t.Error("Expected a violation for a round sent before an earlier round was received")
+	}
+}
@@ -0,0 +1,96 @@
+package transport
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+type Compression string
+
+const (
+	NoCompression Compression = ""
+	Gzip          Compression = "gzip"
+	Flate         Compression = "flate"
+)
+
+var (
+	gzipWriters  = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+	flateWriters = sync.Pool{New: func() any {
+		w, _ := flate.NewWriter(nil, flate.DefaultCompression)
+		return w
+	}}
+)
+
+func ParseCompression(name string) (Compression, error) {
+	switch strings.ToLower(name) {
+	case "", "none":
+		return NoCompression, nil
+	case "gzip":
+		return Gzip, nil
+	case "flate":
+		return Flate, nil
+	default:
+		return NoCompression, fmt.Errorf("unknown compression %q", name)
+	}
+}
+
+func (c Compression) String() string {
+	if c == NoCompression {
+		return "none"
+	}
+	return string(c)
+}
+
+func compress(c Compression, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	switch c {
+	case Gzip:
+		w := gzipWriters.Get().(*gzip.Writer)
+		defer gzipWriters.Put(w)
+		w.Reset(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	case Flate:
+		w := flateWriters.Get().(*flate.Writer)
+		defer flateWriters.Put(w)
+		w.Reset(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown compression %q", c)
+	}
+	return buf.Bytes(), nil
+}
+
+func decompress(c Compression, data []byte) ([]byte, error) {
+	var r io.ReadCloser
+
+	switch c {
+	case Gzip:
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		r = gr
+	case Flate:
+		r = flate.NewReader(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("unknown compression %q", c)
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
@@ -0,0 +1,601 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+const (
+	ackEvery          = 32
+	reconnectAttempts = 6
+)
+
+// Options tunes how links write to the network. Without Batch every frame
+// is written immediately; with it, frames stay in the link's write buffer
+// until Flush, which the engine calls whenever a node is about to wait, so
+// everything a node sends between two waits goes out in a single write. With
+// Compression set, each message is compressed on its own and sent
+// compressed only if that makes it smaller. Latency and Bandwidth, when
+// set, hold every message back until it would have crossed a real link of
+// that kind. Clock, when set, is the local node's logical clock and stamps
+// the messages it sends. OnDisconnect and OnReconnect, when set, are told
+// about every connection a link loses and gets back. PortBase is the port
+// node 0 listens on, DefaultPort if zero; node i listens on PortBase+i.
+type Options struct {
+	PortBase    int
+	NoDelay     bool
+	Batch       bool
+	Compression Compression
+	Latency     LatencyModel
+	Bandwidth   float64
+	Stats       *Stats
+	Clock       *Clock
+
+	OnDisconnect func(peerID int, err error)
+	OnReconnect  func(peerID int, downtime time.Duration, resent int)
+}
+
+type delayedFrame struct {
+	f   frame
+	due time.Time
+}
+
+// frame is one message on the wire. Resend, sent with Ack set to the last
+// frame delivered, asks the peer to send every later frame again.
+type frame struct {
+	Seq    uint64          `json:"seq,omitempty"`
+	Ack    uint64          `json:"ack,omitempty"`
+	Resend bool            `json:"resend,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	Codec  Compression     `json:"codec,omitempty"`
+	Z      []byte          `json:"z,omitempty"`
+}
+
+// Link is a net.Conn to a single neighbor that survives connection loss.
+// Every write is framed with a sequence number and kept until the peer
+// acknowledges it; when the underlying connection breaks, the dialing side
+// redials and both sides resend whatever the other has not seen yet. Frames
+// are delivered exactly once and in order: a frame that arrives after a gap
+// is dropped and the peer is asked to resend from the gap.
+type Link struct {
+	LocalID int
+	PeerID  int
+
+	opts   Options
+	shaper *shaper
+
+	mu       sync.Mutex
+	conn     net.Conn
+	bw       *bufio.Writer
+	enc      *json.Encoder
+	address  string
+	lostAt   time.Time
+	nextSeq  uint64
+	lastRecv uint64
+	lastAck  uint64
+	nacked   bool
+	unacked  []frame
+	delayed  []delayedFrame
+	wake     chan struct{}
+	attached bool
+	closed   bool
+
+	deliverMu sync.Mutex
+	pr        *io.PipeReader
+	pw        *io.PipeWriter
+}
+
+var _ net.Conn = (*Link)(nil)
+
+// NewLink returns an unconnected link to peerID. Writes are buffered until
+// the peer dials in and the link is handed its connection by ServeLinks.
+func NewLink(localID, peerID int, opts Options) *Link {
+	pr, pw := io.Pipe()
+	l := &Link{
+		LocalID: localID,
+		PeerID:  peerID,
+		opts:    opts,
+		shaper:  newShaper(opts, localID, peerID),
+		lostAt:  time.Now(),
+		pr:      pr,
+		pw:      pw,
+	}
+	if l.shaper != nil {
+		l.wake = make(chan struct{}, 1)
+		go l.delayLoop()
+	}
+	return l
+}
+
+// DialLink connects to peerID's listener and completes the link handshake.
+func DialLink(localID, peerID int, opts Options) (*Link, error) {
+	l := NewLink(localID, peerID, opts)
+	l.address = "localhost:" + strconv.Itoa(opts.Port(peerID))
+
+	conn, err := dialWithRetry(l.address, 10)
+	if err != nil {
+		return nil, err
+	}
+	peerAck, r, err := l.handshake(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	l.resume(conn, r, peerAck)
+	return l, nil
+}
+
+// ServeLinks accepts connections on listener and attaches each one to the
+// link registered for the dialing peer, including redials after a drop.
+func ServeLinks(listener net.Listener, links map[int]*Link) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		go func(c net.Conn) {
+			dec := json.NewDecoder(c)
+			var hello types.Message[json.RawMessage]
+			if err := dec.Decode(&hello); err != nil || hello.Type != types.MsgSync {
+				c.Close()
+				return
+			}
+
+			l, ok := links[hello.SenderID]
+			if !ok {
+				c.Close()
+				return
+			}
+			if err := l.accept(c, io.MultiReader(dec.Buffered(), c), hello.Sequence); err != nil {
+				c.Close()
+			}
+		}(conn)
+	}
+}
+
+func (l *Link) Read(p []byte) (int, error) {
+	return l.pr.Read(p)
+}
+
+func (l *Link) Write(p []byte) (int, error) {
+	data := bytes.TrimSpace(p)
+	if !json.Valid(data) {
+		return 0, fmt.Errorf("link %d->%d: write is not a single JSON value", l.LocalID, l.PeerID)
+	}
+
+	f := frame{Data: append(json.RawMessage(nil), data...)}
+	sent := len(data)
+	if l.opts.Compression != NoCompression {
+		z, err := compress(l.opts.Compression, data)
+		if err != nil {
+			return 0, err
+		}
+		// Z travels base64-encoded inside the JSON frame, so that is the
+		// size to beat and to count.
+		if encoded := base64.StdEncoding.EncodedLen(len(z)); encoded < len(data) {
+			f = frame{Codec: l.opts.Compression, Z: z}
+			sent = encoded
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return 0, net.ErrClosed
+	}
+
+	l.nextSeq++
+	f.Seq = l.nextSeq
+	l.unacked = append(l.unacked, f)
+	l.opts.Stats.addPayload(len(data), sent, f.Codec != NoCompression)
+
+	if l.shaper != nil {
+		l.delayed = append(l.delayed, delayedFrame{f: f, due: l.shaper.schedule(sent, time.Now())})
+		l.wakeDelayLoop()
+		return len(p), nil
+	}
+
+	if l.enc != nil {
+		f.Ack = l.lastRecv
+		l.lastAck = l.lastRecv
+		if err := l.send(f); err != nil {
+			go l.fail(l.conn, err)
+		}
+	}
+	return len(p), nil
+}
+
+func (l *Link) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	if l.wake != nil {
+		l.wakeDelayLoop()
+	}
+	if l.bw != nil {
+		l.bw.Flush()
+	}
+	conn := l.conn
+	l.conn, l.bw, l.enc = nil, nil, nil
+	l.mu.Unlock()
+
+	if conn != nil {
+		conn.Close()
+	}
+	return l.pw.Close()
+}
+
+func (l *Link) LocalAddr() net.Addr {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conn == nil {
+		return nil
+	}
+	return l.conn.LocalAddr()
+}
+
+func (l *Link) RemoteAddr() net.Addr {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conn == nil {
+		return nil
+	}
+	return l.conn.RemoteAddr()
+}
+
+func (l *Link) SetDeadline(t time.Time) error      { return nil }
+func (l *Link) SetReadDeadline(t time.Time) error  { return nil }
+func (l *Link) SetWriteDeadline(t time.Time) error { return nil }
+
+func (l *Link) handshake(conn net.Conn) (uint64, io.Reader, error) {
+	l.mu.Lock()
+	hello := types.Message[json.RawMessage]{Type: types.MsgSync, SenderID: l.LocalID, Sequence: l.lastRecv}
+	l.mu.Unlock()
+
+	if err := json.NewEncoder(conn).Encode(hello); err != nil {
+		return 0, nil, err
+	}
+
+	dec := json.NewDecoder(conn)
+	var reply types.Message[json.RawMessage]
+	if err := dec.Decode(&reply); err != nil {
+		return 0, nil, err
+	}
+	if reply.Type != types.MsgSync || reply.SenderID != l.PeerID {
+		return 0, nil, fmt.Errorf("unexpected handshake from node %d", reply.SenderID)
+	}
+	return reply.Sequence, io.MultiReader(dec.Buffered(), conn), nil
+}
+
+func (l *Link) accept(conn net.Conn, r io.Reader, peerAck uint64) error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return net.ErrClosed
+	}
+	old := l.conn
+	l.conn, l.bw, l.enc = nil, nil, nil
+	reply := types.Message[json.RawMessage]{Type: types.MsgSync, SenderID: l.LocalID, Sequence: l.lastRecv}
+	l.mu.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+	if err := json.NewEncoder(conn).Encode(reply); err != nil {
+		return err
+	}
+	l.resume(conn, r, peerAck)
+	return nil
+}
+
+func (l *Link) resume(conn net.Conn, r io.Reader, peerAck uint64) {
+	l.mu.Lock()
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		tcp.SetNoDelay(l.opts.NoDelay)
+	}
+	l.conn = conn
+	l.bw = bufio.NewWriter(countingWriter{w: conn, stats: l.opts.Stats})
+	l.enc = json.NewEncoder(l.bw)
+	l.nacked = false
+	l.trim(peerAck)
+
+	resent := len(l.unacked)
+	err := l.resend()
+
+	downtime := time.Since(l.lostAt)
+	reconnected := l.attached
+	l.attached = true
+	l.mu.Unlock()
+
+	if reconnected && l.opts.OnReconnect != nil {
+		l.opts.OnReconnect(l.PeerID, downtime, resent)
+	}
+	if err != nil {
+		go l.fail(conn, err)
+		return
+	}
+	go l.readLoop(conn, r)
+}
+
+func (l *Link) readLoop(conn net.Conn, r io.Reader) {
+	dec := json.NewDecoder(r)
+	for {
+		var f frame
+		if err := dec.Decode(&f); err != nil {
+			l.fail(conn, err)
+			return
+		}
+		if f.Codec != NoCompression {
+			data, err := decompress(f.Codec, f.Z)
+			if err != nil {
+				l.fail(conn, err)
+				return
+			}
+			f.Data = data
+		}
+
+		l.deliverMu.Lock()
+		l.mu.Lock()
+		if l.conn != conn {
+			l.mu.Unlock()
+			l.deliverMu.Unlock()
+			return
+		}
+		l.trim(f.Ack)
+		if f.Resend {
+			if err := l.resend(); err != nil {
+				go l.fail(conn, err)
+			}
+		}
+
+		deliver := f.Seq != 0 && f.Seq == l.lastRecv+1
+		switch {
+		case deliver:
+			l.lastRecv, l.nacked = f.Seq, false
+		case f.Seq > l.lastRecv+1 && !l.nacked:
+			// A frame went missing. Ask for everything after the last one
+			// delivered, once per gap; the frames up to the resend are
+			// dropped like duplicates.
+			l.nacked = true
+			l.lastAck = l.lastRecv
+			if err := l.sendNow(frame{Ack: l.lastRecv, Resend: true}); err != nil {
+				go l.fail(conn, err)
+			}
+		}
+		if l.lastRecv-l.lastAck >= ackEvery {
+			l.lastAck = l.lastRecv
+			if err := l.send(frame{Ack: l.lastRecv}); err != nil {
+				go l.fail(conn, err)
+			}
+		}
+		l.mu.Unlock()
+
+		if deliver {
+			l.pw.Write(append(f.Data, '\n'))
+		}
+		l.deliverMu.Unlock()
+	}
+}
+
+// delayLoop releases messages held back by the link's latency and bandwidth
+// model once they are due. Messages written while the link is down stay in
+// unacked and go out with the resend on reconnect.
+func (l *Link) delayLoop() {
+	for {
+		l.mu.Lock()
+		if l.closed {
+			l.mu.Unlock()
+			return
+		}
+		if len(l.delayed) == 0 {
+			l.mu.Unlock()
+			<-l.wake
+			continue
+		}
+		next := l.delayed[0]
+		l.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next.due))
+		select {
+		case <-timer.C:
+		case <-l.wake:
+			timer.Stop()
+			continue
+		}
+
+		l.mu.Lock()
+		if len(l.delayed) == 0 || l.delayed[0].f.Seq != next.f.Seq || !l.delayed[0].due.Equal(next.due) {
+			// A resend requeued the frames while this one was waiting.
+			l.mu.Unlock()
+			continue
+		}
+		l.delayed = l.delayed[1:]
+		if l.enc != nil {
+			next.f.Ack = l.lastRecv
+			l.lastAck = l.lastRecv
+			if err := l.sendNow(next.f); err != nil {
+				go l.fail(l.conn, err)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+// resend sends every unacknowledged frame again, in order. Callers must
+// hold l.mu.
+func (l *Link) resend() error {
+	if l.shaper != nil {
+		l.requeue(time.Now())
+		return nil
+	}
+	for _, f := range l.unacked {
+		f.Ack = l.lastRecv
+		if err := l.send(f); err != nil {
+			return err
+		}
+	}
+	l.lastAck = l.lastRecv
+	return l.bw.Flush()
+}
+
+// requeue hands the frames a resend repeats back to the shaper, so they pay
+// the link's bandwidth and latency again instead of skipping the model. The
+// frames already sent go ahead of those still held back, which are pushed
+// behind them to keep the link in order. Callers must hold l.mu.
+func (l *Link) requeue(now time.Time) {
+	var requeued []delayedFrame
+	var last time.Time
+	for _, f := range l.unacked {
+		if len(l.delayed) > 0 && f.Seq >= l.delayed[0].f.Seq {
+			break
+		}
+		last = l.shaper.schedule(frameSize(f), now)
+		requeued = append(requeued, delayedFrame{f: f, due: last})
+	}
+	for _, d := range l.delayed {
+		if d.due.Before(last) {
+			d.due = last
+		}
+		requeued = append(requeued, d)
+	}
+	l.delayed = requeued
+	l.wakeDelayLoop()
+}
+
+// wakeDelayLoop tells delayLoop the held-back frames changed. Callers must
+// hold l.mu.
+func (l *Link) wakeDelayLoop() {
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+}
+
+// frameSize is how many payload bytes f puts on the wire.
+func frameSize(f frame) int {
+	if f.Codec != NoCompression {
+		return base64.StdEncoding.EncodedLen(len(f.Z))
+	}
+	return len(f.Data)
+}
+
+// send encodes f onto the active connection. A batching link only writes
+// it out on the next flush. Callers must hold l.mu.
+func (l *Link) send(f frame) error {
+	if err := l.enc.Encode(f); err != nil {
+		return err
+	}
+	if f.Seq > 0 {
+		l.opts.Stats.addMessage()
+	}
+	if l.opts.Batch {
+		return nil
+	}
+	return l.bw.Flush()
+}
+
+// sendNow sends f and writes it out even on a batching link, for frames
+// that cannot wait for the node's next flush. Callers must hold l.mu.
+func (l *Link) sendNow(f frame) error {
+	if err := l.send(f); err != nil {
+		return err
+	}
+	return l.bw.Flush()
+}
+
+// Flush writes out the frames a batching link holds back.
+func (l *Link) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.flush()
+}
+
+// flush writes out the buffered frames, failing the connection on error.
+// Callers must hold l.mu.
+func (l *Link) flush() error {
+	if l.bw == nil || l.bw.Buffered() == 0 {
+		return nil
+	}
+	if err := l.bw.Flush(); err != nil {
+		go l.fail(l.conn, err)
+		return err
+	}
+	return nil
+}
+
+func (l *Link) trim(ack uint64) {
+	i := 0
+	for i < len(l.unacked) && l.unacked[i].Seq <= ack {
+		i++
+	}
+	l.unacked = l.unacked[i:]
+}
+
+// fail tears down conn if it is still the link's active connection. The
+// dialing side then redials; the accepting side waits for the peer to.
+func (l *Link) fail(conn net.Conn, cause error) {
+	l.mu.Lock()
+	if l.closed || l.conn != conn {
+		l.mu.Unlock()
+		return
+	}
+	l.conn, l.bw, l.enc = nil, nil, nil
+	l.lostAt = time.Now()
+	address := l.address
+	l.mu.Unlock()
+
+	conn.Close()
+	if l.opts.OnDisconnect != nil {
+		l.opts.OnDisconnect(l.PeerID, cause)
+	}
+	if address == "" {
+		return
+	}
+
+	var err error
+	for range reconnectAttempts {
+		var next net.Conn
+		next, err = dialWithRetry(address, reconnectAttempts)
+		if err != nil {
+			break
+		}
+
+		var peerAck uint64
+		var r io.Reader
+		peerAck, r, err = l.handshake(next)
+		if err == nil {
+			l.mu.Lock()
+			closed := l.closed
+			l.mu.Unlock()
+			if closed {
+				next.Close()
+				return
+			}
+			l.resume(next, r, peerAck)
+			return
+		}
+		next.Close()
+	}
+
+	l.pw.CloseWithError(fmt.Errorf("reconnect to node %d: %w", l.PeerID, err))
+	l.Close()
+}
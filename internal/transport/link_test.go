@@ -0,0 +1,236 @@
+package transport
+
+import (
+	"encoding/json"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/InventedSarawak/Distributed-Sorting-Sim/pkg/types"
+)
+
+func newLinkPair(t *testing.T, acceptorID, dialerID int, opts Options) (acceptor, dialer *Link) {
+	t.Helper()
+	return newLinkPairWith(t, acceptorID, dialerID, opts, opts)
+}
+
+// newLinkPairWith is newLinkPair with separate options for each end, for
+// state that belongs to a single node such as its Clock.
+func newLinkPairWith(t *testing.T, acceptorID, dialerID int, acceptorOpts, dialerOpts Options) (acceptor, dialer *Link) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(getCurrentPort(acceptorID)))
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	acceptor = NewLink(acceptorID, dialerID, acceptorOpts)
+	t.Cleanup(func() { acceptor.Close() })
+	go ServeLinks(listener, map[int]*Link{dialerID: acceptor})
+
+	dialer, err = DialLink(dialerID, acceptorID, dialerOpts)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { dialer.Close() })
+	return acceptor, dialer
+}
+
+func TestLinkResendsAfterConnectionLoss(t *testing.T) {
+	const msgCount = 200
+	const acceptorID, dialerID = 60, 61
+	reconnected := make(chan int, 1)
+	acceptor, dialer := newLinkPair(t, acceptorID, dialerID, Options{
+		NoDelay: true,
+		OnReconnect: func(peerID int, downtime time.Duration, resent int) {
+			select {
+			case reconnected <- resent:
+			default:
+			}
+		},
+	})
+
+	inbox := make(chan types.Message[TestPayload], msgCount)
+	go HandleConnection(acceptor, inbox)
+
+	for i := 0; i < msgCount; i++ {
+		if i == msgCount/2 {
+			dialer.mu.Lock()
+			conn := dialer.conn
+			dialer.mu.Unlock()
+			conn.Close()
+		}
+		if err := SendMessage(dialer, types.Message[TestPayload]{SenderID: dialerID, Round: i}); err != nil {
+			t.Fatalf("Send failed at %d: %v", i, err)
+		}
+	}
+
+	for i := 0; i < msgCount; i++ {
+		select {
+		case received := <-inbox:
+			if received.Round != i {
+				t.Fatalf("Order mismatch after reconnect: expected %d, got %d", i, received.Round)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for message %d", i)
+		}
+	}
+
+	select {
+	case <-reconnected:
+	case <-time.After(5 * time.Second):
+		t.Error("Reconnect was never reported")
+	}
+}
+
+func TestLinkShapedResendKeepsOrder(t *testing.T) {
+	const msgCount = 40
+	const latency = 20 * time.Millisecond
+	const acceptorID, dialerID = 70, 71
+	acceptor, dialer := newLinkPair(t, acceptorID, dialerID, Options{
+		NoDelay: true,
+		Latency: uniformLatency{min: latency, max: latency},
+	})
+
+	inbox := make(chan types.Message[TestPayload], msgCount)
+	go HandleConnection(acceptor, inbox)
+
+	start := time.Now()
+	for i := 0; i < msgCount; i++ {
+		if i == msgCount/2 {
+			dialer.mu.Lock()
+			conn := dialer.conn
+			dialer.mu.Unlock()
+			conn.Close()
+		}
+		if err := SendMessage(dialer, types.Message[TestPayload]{SenderID: dialerID, Round: i}); err != nil {
+			t.Fatalf("Send failed at %d: %v", i, err)
+		}
+	}
+
+	for i := 0; i < msgCount; i++ {
+		select {
+		case received := <-inbox:
+			if received.Round != i {
+				t.Fatalf("Order mismatch after shaped resend: expected %d, got %d", i, received.Round)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for message %d", i)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < latency {
+		t.Errorf("Messages arrived after %v, before the %v link latency", elapsed, latency)
+	}
+}
+
+func TestLinkResendsAfterSequenceGap(t *testing.T) {
+	const acceptorID, dialerID = 68, 69
+	acceptor, dialer := newLinkPair(t, acceptorID, dialerID, Options{NoDelay: true})
+
+	inbox := make(chan types.Message[TestPayload], 3)
+	go HandleConnection(acceptor, inbox)
+
+	SendMessage(dialer, types.Message[TestPayload]{SenderID: dialerID, Round: 0})
+
+	// Round 1 is numbered and kept for resending, but never reaches the
+	// wire, so round 2 arrives after a gap.
+	data, err := json.Marshal(types.Message[TestPayload]{SenderID: dialerID, Round: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dialer.mu.Lock()
+	dialer.nextSeq++
+	dialer.unacked = append(dialer.unacked, frame{Seq: dialer.nextSeq, Data: data})
+	dialer.mu.Unlock()
+
+	SendMessage(dialer, types.Message[TestPayload]{SenderID: dialerID, Round: 2})
+
+	for i := 0; i < 3; i++ {
+		select {
+		case received := <-inbox:
+			if received.Round != i {
+				t.Fatalf("Expected round %d, got %d", i, received.Round)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for round %d", i)
+		}
+	}
+	select {
+	case received := <-inbox:
+		t.Errorf("Round %d delivered twice", received.Round)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestLinkBatchHoldsFramesUntilFlush(t *testing.T) {
+	// Few enough to fit the link's write buffer, which writes out on its
+	// own once full.
+	const msgCount = 10
+	const acceptorID, dialerID = 62, 63
+	stats := &Stats{}
+	acceptor, dialer := newLinkPair(t, acceptorID, dialerID, Options{NoDelay: true, Batch: true, Stats: stats})
+
+	inbox := make(chan types.Message[TestPayload], msgCount)
+	go HandleConnection(acceptor, inbox)
+
+	for i := 0; i < msgCount; i++ {
+		SendMessage(dialer, types.Message[TestPayload]{SenderID: dialerID, Round: i})
+	}
+
+	select {
+	case received := <-inbox:
+		t.Fatalf("Round %d arrived before the flush", received.Round)
+	case <-time.After(100 * time.Millisecond):
+	}
+	writes := stats.Snapshot().Writes
+
+	if err := Flush(dialer); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	for i := 0; i < msgCount; i++ {
+		select {
+		case <-inbox:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for message %d", i)
+		}
+	}
+
+	snap := stats.Snapshot()
+	if snap.Messages != msgCount {
+		t.Errorf("Expected %d messages counted, got %d", msgCount, snap.Messages)
+	}
+	if flushWrites := snap.Writes - writes; flushWrites >= msgCount {
+		t.Errorf("Batching did not coalesce writes: %d writes for %d messages", flushWrites, msgCount)
+	}
+}
+
+func TestLinkCompressedRoundTrip(t *testing.T) {
+	const acceptorID, dialerID = 64, 65
+	stats := &Stats{}
+	acceptor, dialer := newLinkPair(t, acceptorID, dialerID, Options{NoDelay: true, Compression: Gzip, Stats: stats})
+
+	inbox := make(chan types.Message[TestPayload], 1)
+	go HandleConnection(acceptor, inbox)
+
+	data := strings.Repeat("sasaki ", 4096)
+	if err := SendMessage(dialer, types.Message[TestPayload]{SenderID: dialerID, Body: TestPayload{Data: data}}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	select {
+	case received := <-inbox:
+		if received.Body.Data != data {
+			t.Error("Data corruption in compressed payload")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout on compressed payload")
+	}
+
+	snap := stats.Snapshot()
+	if snap.Compressed != 1 || snap.SentBytes >= snap.RawBytes {
+		t.Errorf("Expected one compressed message smaller than raw, got %d compressed, %d -> %d bytes", snap.Compressed, snap.RawBytes, snap.SentBytes)
+	}
+}
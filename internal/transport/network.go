@@ -0,0 +1,195 @@
+package transport
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LatencyModel draws the one-way propagation delay of a single message.
+type LatencyModel interface {
+	Sample(r *rand.Rand) time.Duration
+	String() string
+}
+
+type uniformLatency struct {
+	min, max time.Duration
+}
+
+func (u uniformLatency) Sample(r *rand.Rand) time.Duration {
+	if u.max <= u.min {
+		return u.min
+	}
+	return u.min + time.Duration(r.Int63n(int64(u.max-u.min)+1))
+}
+
+func (u uniformLatency) String() string {
+	return fmt.Sprintf("uniform(%v,%v)", u.min, u.max)
+}
+
+type normalLatency struct {
+	mean, stddev time.Duration
+}
+
+func (n normalLatency) Sample(r *rand.Rand) time.Duration {
+	d := n.mean + time.Duration(r.NormFloat64()*float64(n.stddev))
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+func (n normalLatency) String() string {
+	return fmt.Sprintf("normal(%v,%v)", n.mean, n.stddev)
+}
+
+type empiricalLatency struct {
+	path    string
+	samples []time.Duration
+}
+
+func (e empiricalLatency) Sample(r *rand.Rand) time.Duration {
+	return e.samples[r.Intn(len(e.samples))]
+}
+
+func (e empiricalLatency) String() string {
+	return fmt.Sprintf("file(%s, %d samples)", e.path, len(e.samples))
+}
+
+// ParseLatency reads a latency spec of the form "uniform:MIN,MAX",
+// "normal:MEAN,STDDEV" or "file:PATH", where the file lists one duration per
+// line and is sampled uniformly. An empty spec or "none" disables the model.
+func ParseLatency(spec string) (LatencyModel, error) {
+	kind, args, _ := strings.Cut(spec, ":")
+	kind = strings.ToLower(kind)
+
+	switch kind {
+	case "", "none":
+		return nil, nil
+	case "uniform", "normal":
+		lo, hi, ok := strings.Cut(args, ",")
+		if !ok {
+			return nil, fmt.Errorf("latency %q: expected two durations", spec)
+		}
+		a, err := time.ParseDuration(strings.TrimSpace(lo))
+		if err != nil {
+			return nil, fmt.Errorf("latency %q: %w", spec, err)
+		}
+		b, err := time.ParseDuration(strings.TrimSpace(hi))
+		if err != nil {
+			return nil, fmt.Errorf("latency %q: %w", spec, err)
+		}
+		if kind == "uniform" {
+			return uniformLatency{min: a, max: b}, nil
+		}
+		return normalLatency{mean: a, stddev: b}, nil
+	case "file":
+		return loadLatencyFile(args)
+	default:
+		return nil, fmt.Errorf("unknown latency model %q", kind)
+	}
+}
+
+func loadLatencyFile(path string) (LatencyModel, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	model := empiricalLatency{path: path}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		d, err := time.ParseDuration(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		model.samples = append(model.samples, d)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(model.samples) == 0 {
+		return nil, fmt.Errorf("%s: no latency samples", path)
+	}
+	return model, nil
+}
+
+// ParseBandwidth reads a per-link bandwidth cap in bytes per second, with an
+// optional KB, MB or GB suffix. An empty spec or "0" means unlimited.
+func ParseBandwidth(spec string) (float64, error) {
+	spec = strings.ToUpper(strings.TrimSpace(spec))
+	if spec == "" {
+		return 0, nil
+	}
+
+	scale := 1.0
+	for _, unit := range []struct {
+		suffix string
+		scale  float64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(spec, unit.suffix) {
+			spec = strings.TrimSuffix(spec, unit.suffix)
+			scale = unit.scale
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(spec), 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid bandwidth %q", spec)
+	}
+	return value * scale, nil
+}
+
+// shaper decides when each message written to a link reaches the peer.
+// Messages leave one after another at the link's bandwidth, then spend a
+// sampled latency in flight; a message never overtakes the one before it.
+type shaper struct {
+	latency    LatencyModel
+	bandwidth  float64
+	rng        *rand.Rand
+	lastDepart time.Time
+	lastArrive time.Time
+}
+
+func newShaper(opts Options, localID, peerID int) *shaper {
+	if opts.Latency == nil && opts.Bandwidth <= 0 {
+		return nil
+	}
+	seed := time.Now().UnixNano() + int64(localID)*7919 + int64(peerID)
+	return &shaper{
+		latency:   opts.Latency,
+		bandwidth: opts.Bandwidth,
+		rng:       rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (s *shaper) schedule(size int, now time.Time) time.Time {
+	depart := now
+	if depart.Before(s.lastDepart) {
+		depart = s.lastDepart
+	}
+	if s.bandwidth > 0 {
+		depart = depart.Add(time.Duration(float64(size) / s.bandwidth * float64(time.Second)))
+	}
+	s.lastDepart = depart
+
+	arrive := depart
+	if s.latency != nil {
+		arrive = arrive.Add(s.latency.Sample(s.rng))
+	}
+	if arrive.Before(s.lastArrive) {
+		arrive = s.lastArrive
+	}
+	s.lastArrive = arrive
+	return arrive
+}
@@ -0,0 +1,106 @@
+package transport
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseLatency(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"uniform:1ms,5ms", "uniform(1ms,5ms)"},
+		{"Uniform:1ms, 5ms", "uniform(1ms,5ms)"},
+		{"NORMAL:10ms,2ms", "normal(10ms,2ms)"},
+		{"normal:10ms,2ms", "normal(10ms,2ms)"},
+	}
+	for _, tt := range tests {
+		model, err := ParseLatency(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseLatency(%q): %v", tt.spec, err)
+		}
+		if got := model.String(); got != tt.want {
+			t.Errorf("ParseLatency(%q) = %s, expected %s", tt.spec, got, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"", "none", "None"} {
+		if model, err := ParseLatency(spec); err != nil || model != nil {
+			t.Errorf("ParseLatency(%q) = %v, %v, expected no model", spec, model, err)
+		}
+	}
+	for _, spec := range []string{"uniform:1ms", "uniform:1ms,soon", "normal:x,1ms", "pareto:1ms,2ms", "file:" + filepath.Join(t.TempDir(), "missing")} {
+		if _, err := ParseLatency(spec); err == nil {
+			t.Errorf("ParseLatency(%q): expected an error", spec)
+		}
+	}
+}
+
+func TestLatencySamplesStayInRange(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	uniform, _ := ParseLatency("uniform:2ms,4ms")
+	normal, _ := ParseLatency("normal:0s,10ms")
+	for range 1000 {
+		if d := uniform.Sample(r); d < 2*time.Millisecond || d > 4*time.Millisecond {
+			t.Fatalf("Uniform sample %v outside [2ms, 4ms]", d)
+		}
+		if d := normal.Sample(r); d < 0 {
+			t.Fatalf("Normal sample %v is negative", d)
+		}
+	}
+}
+
+func TestLatencyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rtt.txt")
+	if err := os.WriteFile(path, []byte("# one-way delays\n3ms\n\n7ms\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	model, err := ParseLatency("FILE:" + path)
+	if err != nil {
+		t.Fatalf("ParseLatency: %v", err)
+	}
+	r := rand.New(rand.NewSource(1))
+	for range 100 {
+		if d := model.Sample(r); d != 3*time.Millisecond && d != 7*time.Millisecond {
+			t.Fatalf("Sample %v is not one of the file's delays", d)
+		}
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty.txt")
+	if err := os.WriteFile(empty, []byte("# nothing\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseLatency("file:" + empty); err == nil {
+		t.Error("Expected an error for a file without samples")
+	}
+}
+
+func TestParseBandwidth(t *testing.T) {
+	tests := map[string]float64{
+		"":      0,
+		"0":     0,
+		"512":   512,
+		"100B":  100,
+		"2KB":   2048,
+		"1.5mb": 1.5 * (1 << 20),
+		"1GB":   1 << 30,
+	}
+	for spec, want := range tests {
+		got, err := ParseBandwidth(spec)
+		if err != nil {
+			t.Fatalf("ParseBandwidth(%q): %v", spec, err)
+		}
+		if got != want {
+			t.Errorf("ParseBandwidth(%q) = %v, expected %v", spec, got, want)
+		}
+	}
+	for _, spec := range []string{"fast", "-1KB", "KB"} {
+		if _, err := ParseBandwidth(spec); err == nil {
+			t.Errorf("ParseBandwidth(%q): expected an error", spec)
+		}
+	}
+}
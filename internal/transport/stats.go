@@ -0,0 +1,103 @@
+package transport
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// Stats counts link traffic across every node of a run. A nil *Stats is
+// valid and records nothing.
+type Stats struct {
+	messages   atomic.Uint64
+	compressed atomic.Uint64
+	rawBytes   atomic.Uint64
+	sentBytes  atomic.Uint64
+	writes     atomic.Uint64
+	bytes      atomic.Uint64
+	received   atomic.Uint64
+	latency    atomic.Int64
+	maxLatency atomic.Int64
+}
+
+type StatsSnapshot struct {
+	Messages    uint64
+	Compressed  uint64
+	RawBytes    uint64
+	SentBytes   uint64
+	Writes      uint64
+	Bytes       uint64
+	Received    uint64
+	MeanLatency time.Duration
+	MaxLatency  time.Duration
+}
+
+func (s *Stats) addMessage() {
+	if s != nil {
+		s.messages.Add(1)
+	}
+}
+
+func (s *Stats) addPayload(raw, sent int, compressed bool) {
+	if s == nil {
+		return
+	}
+	s.rawBytes.Add(uint64(raw))
+	s.sentBytes.Add(uint64(sent))
+	if compressed {
+		s.compressed.Add(1)
+	}
+}
+
+func (s *Stats) addWrite(n int) {
+	if s != nil {
+		s.writes.Add(1)
+		s.bytes.Add(uint64(n))
+	}
+}
+
+// ObserveLatency records the send-to-receive delay of one delivered message.
+func (s *Stats) ObserveLatency(d time.Duration) {
+	if s == nil {
+		return
+	}
+	s.received.Add(1)
+	s.latency.Add(int64(d))
+	for {
+		current := s.maxLatency.Load()
+		if int64(d) <= current || s.maxLatency.CompareAndSwap(current, int64(d)) {
+			return
+		}
+	}
+}
+
+func (s *Stats) Snapshot() StatsSnapshot {
+	if s == nil {
+		return StatsSnapshot{}
+	}
+	snap := StatsSnapshot{
+		Messages:   s.messages.Load(),
+		Compressed: s.compressed.Load(),
+		RawBytes:   s.rawBytes.Load(),
+		SentBytes:  s.sentBytes.Load(),
+		Writes:     s.writes.Load(),
+		Bytes:      s.bytes.Load(),
+		Received:   s.received.Load(),
+		MaxLatency: time.Duration(s.maxLatency.Load()),
+	}
+	if snap.Received > 0 {
+		snap.MeanLatency = time.Duration(s.latency.Load() / int64(snap.Received))
+	}
+	return snap
+}
+
+type countingWriter struct {
+	w     io.Writer
+	stats *Stats
+}
+
+func (c countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.stats.addWrite(n)
+	return n, err
+}
@@ -51,10 +51,25 @@ func SendMessage[Payload any](conn net.Conn, message types.Message[Payload]) err
 	if conn == nil {
 		return fmt.Errorf("connection not established")
 	}
+	if message.Timestamp.IsZero() {
+		message.Timestamp = time.Now()
+	}
+	if l := linkOf(conn); l != nil && l.opts.Clock != nil {
+		message.Lamport, message.Vector = l.opts.Clock.send(message.Type, message.Round)
+	}
 
 	return json.NewEncoder(conn).Encode(message)
 }
 
+// Flush writes out whatever the Link behind conn holds back for batching.
+// Connections that are not links are left alone.
+func Flush(conn net.Conn) error {
+	if l := linkOf(conn); l != nil {
+		return l.Flush()
+	}
+	return nil
+}
+
 func DialNeighbor(targetID int) (net.Conn, error) {
 	port := getCurrentPort(targetID)
 	address := "localhost:" + strconv.Itoa(port)
@@ -80,3 +95,11 @@ func dialWithRetry(address string, maxRetries int) (net.Conn, error) {
 func getCurrentPort(id int) int {
 	return DefaultPort + id
 }
+
+// Port returns the port node id listens on.
+func (o Options) Port(id int) int {
+	if o.PortBase == 0 {
+		return getCurrentPort(id)
+	}
+	return o.PortBase + id
+}
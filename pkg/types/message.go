@@ -1,6 +1,7 @@
 package types
 
 import (
+	"fmt"
 	"net"
 	"time"
 )
@@ -10,8 +11,27 @@ type Direction string
 const (
 	Left  Direction = "LEFT"
 	Right Direction = "RIGHT"
+	Up    Direction = "UP"
+	Down  Direction = "DOWN"
+
+	Parent Direction = "PARENT"
 )
 
+// Dimension labels a hypercube link: the neighbor whose ID differs in bit d.
+func Dimension(d int) Direction {
+	return Direction(fmt.Sprintf("DIM%d", d))
+}
+
+// Peer labels the link to node id in a fully connected network.
+func Peer(id int) Direction {
+	return Direction(fmt.Sprintf("PEER%d", id))
+}
+
+// Child labels the link to a tree node's i-th child.
+func Child(i int) Direction {
+	return Direction(fmt.Sprintf("CHILD%d", i))
+}
+
 type Position string
 
 const (
@@ -28,6 +48,9 @@ const (
 	MsgSync MessageType = "SYNC"
 	MsgTerm MessageType = "TERM"
 	MsgAck  MessageType = "ACK"
+
+	MsgElect MessageType = "ELECT"
+	MsgCount MessageType = "COUNT"
 )
 
 type AlgorithmType int
@@ -36,20 +59,95 @@ const (
 	OddEven AlgorithmType = iota
 	Sasaki
 	Alternate
+	RingRank
+	Shearsort
+	Bitonic
+	TreeMerge
+	SampleSort
+	NetworkSort
 )
 
+type Topology string
+
+const (
+	Line      Topology = "line"
+	Ring      Topology = "ring"
+	Mesh      Topology = "mesh"
+	Hypercube Topology = "hypercube"
+	Tree      Topology = "tree"
+	Complete  Topology = "complete"
+)
+
+// Topology returns the network an algorithm runs on.
+func (a AlgorithmType) Topology() Topology {
+	switch a {
+	case RingRank:
+		return Ring
+	case Shearsort:
+		return Mesh
+	case Bitonic:
+		return Hypercube
+	case TreeMerge:
+		return Tree
+	case SampleSort, NetworkSort:
+		return Complete
+	default:
+		return Line
+	}
+}
+
 type InputType int
 
 const (
 	Random InputType = iota
 	Sorted
 	Reverse
+	// NearlySorted is sorted input with every value displaced by less than
+	// NearlySortedWindow positions.
+	NearlySorted
+)
+
+const NearlySortedWindow = 8
+
+// TerminationMode selects how adaptive algorithms agree that they are done.
+type TerminationMode string
+
+const (
+	// MessageTermination runs a convergecast of MsgTerm votes over the links.
+	MessageTermination TerminationMode = "message"
+	// SharedTermination counts votes in the engine's shared memory, which only
+	// works while all nodes run in one process.
+	SharedTermination TerminationMode = "shared"
+)
+
+type ValueType string
+
+const (
+	IntValues    ValueType = "int"
+	FloatValues  ValueType = "float"
+	StringValues ValueType = "string"
+	RecordValues ValueType = "record"
 )
 
 type Config struct {
 	NodeCount uint
+	BlockSize uint
 	InputType InputType
+	ValueType ValueType
 	Algorithm AlgorithmType
+	Topology  Topology
+	Rows      uint
+
+	// GatherRoot leaves a tree sort's result at the root instead of
+	// redistributing it over the nodes.
+	GatherRoot bool
+
+	// Stable breaks block Sasaki's ties by initial slot.
+	Stable bool
+
+	// Adaptive stops Odd-Even once no node has changed for two rounds.
+	Adaptive    bool
+	Termination TerminationMode
 }
 
 type Message[Payload any] struct {
@@ -61,18 +159,62 @@ type Message[Payload any] struct {
 	IncomingDirection Direction   `json:"incoming_direction"`
 	Timestamp         time.Time   `json:"timestamp"`
 	Sequence          uint64      `json:"sequence"`
+
+	// Lamport and Vector are the sender's logical clocks, set by the
+	// transport when clocks are enabled.
+	Lamport uint64   `json:"lamport,omitempty"`
+	Vector  []uint64 `json:"vector,omitempty"`
+}
+
+// StepReceiver hands out the message a neighbor sent for a given round,
+// holding back messages that arrive early.
+type StepReceiver[Payload any] interface {
+	GetStepMessage(round int) Message[Payload]
+}
+
+// Neighbor is one link of a node. Label names the port the link occupies:
+// LEFT/RIGHT on a line or ring, also UP/DOWN on a mesh, DIM<d> on a
+// hypercube, PARENT/CHILD<i> on a tree, PEER<id> in a fully connected
+// network. Messages arriving on Conn are delivered to Inbox and read
+// through Buffer.
+type Neighbor[Payload any] struct {
+	ID     int
+	Label  Direction
+	Conn   net.Conn
+	Inbox  chan Message[Payload]
+	Buffer StepReceiver[Payload]
+
+	// Control messages (MsgTerm) are kept apart from the algorithm's, so
+	// their rounds never collide in Buffer.
+	ControlInbox chan Message[Payload]
+	Control      StepReceiver[Payload]
 }
 
+// Node is one process of the simulation. Rank is a ring node's distance to
+// the right of the ring's leader, set by ring discovery.
 type Node[Payload any] struct {
 	ID        int
 	Position  Position
 	TotalNode int
+	Rank      int
 	Value     Payload
 	Round     int
 
-	LeftConn  net.Conn
-	RightConn net.Conn
+	Neighbors map[Direction]*Neighbor[Payload]
+}
+
+// Neighbor returns the neighbor on the given port, or nil if the node has
+// no link there.
+func (n *Node[Payload]) Neighbor(label Direction) *Neighbor[Payload] {
+	return n.Neighbors[label]
+}
 
-	LeftInbox  chan Message[Payload]
-	RightInbox chan Message[Payload]
+// NeighborByID returns the neighbor with the given node ID, or nil.
+func (n *Node[Payload]) NeighborByID(id int) *Neighbor[Payload] {
+	for _, nb := range n.Neighbors {
+		if nb.ID == id {
+			return nb
+		}
+	}
+	return nil
 }
@@ -0,0 +1,22 @@
+package types
+
+import (
+	"cmp"
+	"fmt"
+)
+
+// Record is a key/value pair sorted by Key. Index is the record's position
+// in the initial array and travels with it so stability can be checked.
+type Record struct {
+	Key     int    `json:"key"`
+	Payload string `json:"payload"`
+	Index   int    `json:"index"`
+}
+
+func CompareRecords(a, b Record) int {
+	return cmp.Compare(a.Key, b.Key)
+}
+
+func (r Record) String() string {
+	return fmt.Sprintf("%d:%s", r.Key, r.Payload)
+}
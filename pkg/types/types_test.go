@@ -0,0 +1,58 @@
+package types
+
+import "testing"
+
+func TestAlgorithmTopology(t *testing.T) {
+	tests := map[AlgorithmType]Topology{
+		OddEven:     Line,
+		Sasaki:      Line,
+		Alternate:   Line,
+		RingRank:    Ring,
+		Shearsort:   Mesh,
+		Bitonic:     Hypercube,
+		TreeMerge:   Tree,
+		SampleSort:  Complete,
+		NetworkSort: Complete,
+	}
+	for algorithm, topology := range tests {
+		if got := algorithm.Topology(); got != topology {
+			t.Errorf("Algorithm %d runs on %s, expected %s", algorithm, got, topology)
+		}
+	}
+}
+
+func TestNeighborLookup(t *testing.T) {
+	n := &Node[int]{
+		ID: 5,
+		Neighbors: map[Direction]*Neighbor[int]{
+			Dimension(0): {ID: 4, Label: Dimension(0)},
+			Dimension(1): {ID: 7, Label: Dimension(1)},
+		},
+	}
+	if nb := n.Neighbor("DIM1"); nb == nil || nb.ID != 7 {
+		t.Errorf("Neighbor(DIM1) = %v, expected node 7", nb)
+	}
+	if nb := n.Neighbor(Left); nb != nil {
+		t.Errorf("Neighbor(LEFT) = %v, expected none", nb)
+	}
+	if nb := n.NeighborByID(4); nb == nil || nb.Label != Dimension(0) {
+		t.Errorf("NeighborByID(4) = %v, expected the DIM0 link", nb)
+	}
+	if nb := n.NeighborByID(6); nb != nil {
+		t.Errorf("NeighborByID(6) = %v, expected none", nb)
+	}
+}
+
+func TestCompareRecordsIgnoresPayload(t *testing.T) {
+	a := Record{Key: 3, Payload: "item-9", Index: 9}
+	b := Record{Key: 3, Payload: "item-1", Index: 1}
+	if CompareRecords(a, b) != 0 {
+		t.Errorf("Records with equal keys compared unequal: %v, %v", a, b)
+	}
+	if CompareRecords(Record{Key: 2}, a) >= 0 {
+		t.Error("Expected key 2 before key 3")
+	}
+	if s := a.String(); s != "3:item-9" {
+		t.Errorf("Record printed as %q", s)
+	}
+}
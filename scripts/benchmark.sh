@@ -1,13 +1,35 @@
 #!/bin/bash
 
-if [ ! -f "bin/odd_even" ] || [ ! -f "bin/sasaki" ] || [ ! -f "bin/alternative" ]; then
+if [ ! -f "bin/odd_even" ] || [ ! -f "bin/sasaki" ] || [ ! -f "bin/alternative" ] || [ ! -f "bin/ring_sort" ] || [ ! -f "bin/shearsort" ] || [ ! -f "bin/bitonic" ] || [ ! -f "bin/tree_merge" ] || [ ! -f "bin/sample_sort" ] || [ ! -f "bin/network_sort" ]; then
     echo "Error: Binaries not found. Please run 'task build' or 'go build' first."
     exit 1
 fi
 
-NODE_COUNTS=(1000 2000 3000 5000)
+NODE_COUNTS=(1000 1024 2000 2048 3000 5000)
 INPUT_TYPE="random"
+# Each entry is a set of transport flags; the benchmark runs every algorithm
+# once per entry so the throughput/latency trade-off can be compared.
+TRANSPORT_CONFIGS=("-batch=false" "-batch" "-nodelay=false")
 RESULTS_FILE="results.txt"
+SUMMARY_FILE=$(mktemp)
+trap 'rm -f "$SUMMARY_FILE"' EXIT
+
+# run LABEL BINARY ARGS... runs one benchmark, appends its output to the
+# results file and adds its messages per write, throughput and latency to
+# the summary table.
+run() {
+    local label="$1"
+    shift
+    echo "Running $label..."
+    local output
+    output=$("$@" -input-type $INPUT_TYPE -benchmark)
+    echo "$output" >> "$RESULTS_FILE"
+    local per_write=$(echo "$output" | sed -n 's/^Messages: .*(\(.*\) msgs\/write).*/\1/p')
+    local throughput=$(echo "$output" | sed -n 's/^Throughput: \(.*\) msgs\/s/\1/p')
+    local mean=$(echo "$output" | sed -n 's/^Latency: mean \(.*\), max .*/\1/p')
+    local max=$(echo "$output" | sed -n 's/^Latency: mean .*, max \(.*\)/\1/p')
+    printf "%-58s %10s %12s %14s %14s\n" "$label" "$per_write" "$throughput" "$mean" "$max" >> "$SUMMARY_FILE"
+}
 
 # Clear the results file
 > "$RESULTS_FILE"
@@ -23,16 +45,40 @@ for N in "${NODE_COUNTS[@]}"; do
     echo "    Testing Node Count: $N" >> "$RESULTS_FILE"
     echo "----------------------------------------------------------" >> "$RESULTS_FILE"
 
-    echo "Running Odd-Even for Node Count $N..."
-    ./bin/odd_even -node-count $N -input-type $INPUT_TYPE -benchmark >> "$RESULTS_FILE"
+    for TRANSPORT in "${TRANSPORT_CONFIGS[@]}"; do
+        run "Odd-Even, $N nodes ($TRANSPORT)" ./bin/odd_even -node-count $N $TRANSPORT
+        run "Sasaki, $N nodes ($TRANSPORT)" ./bin/sasaki -node-count $N $TRANSPORT
+        run "Alternative, $N nodes ($TRANSPORT)" ./bin/alternative -node-count $N $TRANSPORT
+        run "Shearsort, $N nodes ($TRANSPORT)" ./bin/shearsort -node-count $N $TRANSPORT
+
+        # Bitonic sort needs a power-of-two node count.
+        if [ $((N & (N - 1))) -eq 0 ]; then
+            run "Bitonic, $N nodes ($TRANSPORT)" ./bin/bitonic -node-count $N $TRANSPORT
+        fi
 
-    echo "Running Sasaki for Node Count $N..."
-    ./bin/sasaki -node-count $N -input-type $INPUT_TYPE -benchmark >> "$RESULTS_FILE"
+        # Tree merge nodes stream several rounds of chunks to their parent
+        # before they next wait, so this is where batching saves writes.
+        run "Tree Merge, $N nodes ($TRANSPORT)" ./bin/tree_merge -node-count $N $TRANSPORT
 
-    echo "Running Alternative for Node Count $N..."
-    ./bin/alternative -node-count $N -input-type $INPUT_TYPE -benchmark >> "$RESULTS_FILE"
+        # Sample sort needs a link between every pair of nodes, so it sorts
+        # about the same number of values on 32 nodes with larger blocks.
+        run "Sample Sort, $N values on 32 nodes ($TRANSPORT)" ./bin/sample_sort -node-count 32 -block-size $((N / 32)) $TRANSPORT
+        run "Batcher Network, $N values on 32 nodes ($TRANSPORT)" ./bin/network_sort -node-count 32 -block-size $((N / 32)) $TRANSPORT
+
+        # Ring rank sort sends O(N^2) messages, so it is skipped for the largest runs.
+        if [ "$N" -le 2000 ]; then
+            run "Ring Rank, $N nodes ($TRANSPORT)" ./bin/ring_sort -node-count $N $TRANSPORT
+        fi
+    done
 done
 
+echo "" >> "$RESULTS_FILE"
+echo "==========================================================" >> "$RESULTS_FILE"
+echo "    Summary" >> "$RESULTS_FILE"
+echo "==========================================================" >> "$RESULTS_FILE"
+printf "%-58s %10s %12s %14s %14s\n" "Run" "Msgs/write" "Msgs/s" "Mean latency" "Max latency" >> "$RESULTS_FILE"
+cat "$SUMMARY_FILE" >> "$RESULTS_FILE"
+
 echo "" >> "$RESULTS_FILE"
 echo "==========================================================" >> "$RESULTS_FILE"
 echo "    Benchmark Complete" >> "$RESULTS_FILE"